@@ -0,0 +1,249 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// AWS Lambda deployment package types, named as in the Lambda API.
+const (
+	lambdaPackageZip   = "zip"   // .zip archive on a managed runtime
+	lambdaPackageImage = "image" // Container image
+)
+
+// lambdaImageMarkers are files present in container-image Lambda functions:
+// the AWS base image entrypoint and the Runtime Interface Emulator.
+var lambdaImageMarkers = []string{
+	"/lambda-entrypoint.sh",
+	"/usr/local/bin/aws-lambda-rie",
+}
+
+// AWS execution environments, classified from AWS_EXECUTION_ENV.
+const (
+	awsECSFargate = "ecs-fargate" // ECS task on Fargate
+	awsECSEC2     = "ecs-ec2"     // ECS task on an EC2 container instance
+	awsLambda     = "lambda"      // Lambda function, suffixed with its runtime
+	awsAppRunner  = "app-runner"  // App Runner service
+)
+
+// isLambda returns true if running in an AWS Lambda function.
+func isLambda() bool {
+	// Check if the AWS_LAMBDA_FUNCTION_NAME environment variable is set.
+	if _, ok := EnvironmentVariables["AWS_LAMBDA_FUNCTION_NAME"]; ok {
+		return true
+	}
+
+	return strings.HasPrefix(getAWSExecutionEnv(), awsLambda)
+}
+
+// isECS returns true if running as an Amazon ECS task.
+func isECS() bool {
+	if strings.HasPrefix(getAWSExecutionEnv(), "ecs-") {
+		return true
+	}
+
+	// Check if an ECS task metadata endpoint is set.
+	for _, v := range []string{"ECS_CONTAINER_METADATA_URI", "ECS_CONTAINER_METADATA_URI_V4"} {
+		if _, ok := EnvironmentVariables[v]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getAWSExecutionEnv returns the AWS execution environment classified from
+// the AWS_EXECUTION_ENV variable AWS compute services set, or "" if it is
+// unset or unknown.
+func getAWSExecutionEnv() string {
+	return parseAWSExecutionEnv(EnvironmentVariables["AWS_EXECUTION_ENV"])
+}
+
+// parseAWSExecutionEnv classifies an AWS_EXECUTION_ENV value, such as
+// AWS_ECS_FARGATE, AWS_ECS_EC2 or AWS_Lambda_python3.12. Lambda runtimes are
+// reported as lambda-<runtime>.
+func parseAWSExecutionEnv(v string) string {
+	parts := strings.SplitN(v, "_", 3)
+	if len(parts) < 2 || parts[0] != "AWS" {
+		return ""
+	}
+
+	switch parts[1] {
+	case "ECS":
+		if len(parts) != 3 {
+			return ""
+		}
+
+		switch parts[2] {
+		case "FARGATE":
+			return awsECSFargate
+		case "EC2":
+			return awsECSEC2
+		}
+	case "Lambda":
+		if len(parts) == 3 && parts[2] != "" {
+			return awsLambda + "-" + parts[2]
+		}
+
+		return awsLambda
+	case "AppRunner":
+		return awsAppRunner
+	}
+
+	return ""
+}
+
+// getLambdaPackageType returns whether the Lambda function was deployed as a
+// .zip archive or a container image, or "" if not running in Lambda.
+func getLambdaPackageType() string {
+	if !isLambda() {
+		return ""
+	}
+
+	for _, p := range lambdaImageMarkers {
+		if _, err := os.Stat(p); err == nil {
+			return lambdaPackageImage
+		}
+	}
+
+	return lambdaPackageZip
+}
+
+// awsIMDSURL is the base URL of the EC2 instance metadata service.
+var awsIMDSURL = "http://169.254.169.254/latest"
+
+// isEC2 returns true if running on an EC2 instance: Nitro instances report
+// Amazon EC2 as the DMI system vendor, and Xen instances carry an Amazon BIOS
+// version.
+func isEC2() bool {
+	if v, err := readDMI("sys_vendor"); err == nil && v == "Amazon EC2" {
+		return true
+	}
+
+	v, err := readDMI("bios_version")
+	return err == nil && strings.Contains(v, "amazon")
+}
+
+// awsIdentity is the subset of the EC2 instance identity document used for
+// inventory.
+type awsIdentity struct {
+	AccountID  string `json:"accountId"`
+	InstanceID string `json:"instanceId"`
+	Region     string `json:"region"`
+}
+
+// getAWSIdentity returns the EC2 instance identity document, or nil if not
+// running on EC2 or IMDS does not answer. IMDS is only queried when DMI
+// identifies EC2, so other hosts are never probed. An IMDSv2 session token is
+// requested first, as instances may require it; without one the request is
+// made as IMDSv1. Both requests share one probe timeout per attempt; the token
+// request gets only a quarter of it, so a PUT that never answers (as when the
+// response hop limit is 1 and the caller is a container) leaves time for the
+// IMDSv1 fallback.
+func getAWSIdentity(c *config) *awsIdentity {
+	if !c.network || !isEC2() {
+		return nil
+	}
+
+	var identity *awsIdentity
+
+	client := &http.Client{}
+	c.probe(func() bool {
+		ctx, cancel := context.WithTimeout(c.ctx, c.probeTimeout())
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, awsIMDSURL+"/dynamic/instance-identity/document", nil)
+		if err != nil {
+			return false
+		}
+
+		if token := getIMDSToken(ctx, client, c.probeTimeout()/4); token != "" {
+			req.Header.Set("X-aws-ec2-metadata-token", token)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return false
+		}
+
+		var id awsIdentity
+		if err := json.NewDecoder(resp.Body).Decode(&id); err != nil {
+			return false
+		}
+
+		identity = &id
+		return true
+	})
+
+	return identity
+}
+
+// getIMDSToken returns an IMDSv2 session token, or "" if none is issued
+// within timeout.
+func getIMDSToken(ctx context.Context, client *http.Client, timeout time.Duration) string {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, awsIMDSURL+"/api/token", nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	var b strings.Builder
+	if _, err := io.Copy(&b, io.LimitReader(resp.Body, 256)); err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// accountID returns the AWS account ID, or "" if ai is nil.
+func (ai *awsIdentity) accountID() string {
+	if ai == nil {
+		return ""
+	}
+
+	return ai.AccountID
+}
+
+// instanceID returns the EC2 instance ID, or "" if ai is nil.
+func (ai *awsIdentity) instanceID() string {
+	if ai == nil {
+		return ""
+	}
+
+	return ai.InstanceID
+}
+
+// region returns the AWS region, or "" if ai is nil.
+func (ai *awsIdentity) region() string {
+	if ai == nil {
+		return ""
+	}
+
+	return ai.Region
+}
@@ -0,0 +1,280 @@
+package criprof
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetLambdaPackageType(t *testing.T) {
+	lambdaEnv := map[string]string{
+		"AWS_LAMBDA_FUNCTION_NAME": "resize",
+		"LAMBDA_TASK_ROOT":         "/var/task",
+	}
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		marker bool
+		want   string
+	}{
+		{"zip", lambdaEnv, false, lambdaPackageZip},
+		{"image", lambdaEnv, true, lambdaPackageImage},
+		{"not lambda", nil, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			marker := filepath.Join(dir, "lambda-entrypoint.sh")
+			if tt.marker {
+				writeTestFile(t, dir, "lambda-entrypoint.sh", "#!/bin/sh\n")
+			}
+
+			old := lambdaImageMarkers
+			lambdaImageMarkers = []string{marker}
+			t.Cleanup(func() { lambdaImageMarkers = old })
+
+			withEnvironment(t, tt.env)
+
+			if got := getLambdaPackageType(); got != tt.want {
+				t.Errorf("getLambdaPackageType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAWSExecutionEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"AWS_ECS_FARGATE", awsECSFargate},
+		{"AWS_ECS_EC2", awsECSEC2},
+		{"AWS_Lambda_java11", "lambda-java11"},
+		{"AWS_Lambda_nodejs18.x", "lambda-nodejs18.x"},
+		{"AWS_Lambda_python3.12", "lambda-python3.12"},
+		{"AWS_Lambda_Image", "lambda-Image"},
+		{"AWS_AppRunner", awsAppRunner},
+		{"AWS_ECS_EXTERNAL", ""},
+		{"CloudShell", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseAWSExecutionEnv(tt.value); got != tt.want {
+				t.Errorf("parseAWSExecutionEnv(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAWSExecutionEnvScheduler(t *testing.T) {
+	tests := []struct {
+		value     string
+		scheduler string
+		flavor    string
+	}{
+		{"AWS_ECS_FARGATE", schedulerECS, flavorECSFargate},
+		{"AWS_ECS_EC2", schedulerECS, flavorECSEC2},
+		{"AWS_Lambda_java11", schedulerLambda, ""},
+		{"AWS_AppRunner", schedulerAppRunner, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			withEnvironment(t, map[string]string{"AWS_EXECUTION_ENV": tt.value})
+
+			sch := getScheduler(newConfig(WithoutNetwork()))
+			if sch != tt.scheduler {
+				t.Errorf("getScheduler() = %q, want %q", sch, tt.scheduler)
+			}
+
+			if got := getSchedulerFlavor(sch, nil); got != tt.flavor {
+				t.Errorf("getSchedulerFlavor() = %q, want %q", got, tt.flavor)
+			}
+		})
+	}
+}
+
+const testIdentityDocument = `{
+  "accountId" : "123456789012",
+  "architecture" : "x86_64",
+  "availabilityZone" : "us-east-1a",
+  "imageId" : "ami-0abcdef1234567890",
+  "instanceId" : "i-0a1b2c3d4e5f67890",
+  "instanceType" : "m5.large",
+  "region" : "us-east-1"
+}`
+
+// withAWSIMDS serves the identity document from a fake IMDS. With imdsV2 it
+// issues session tokens and requires one; otherwise it behaves as IMDSv1. It
+// returns the number of requests served.
+func withAWSIMDS(t *testing.T, imdsV2 bool) *int32 {
+	t.Helper()
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch {
+		case r.URL.Path == "/latest/api/token" && r.Method == http.MethodPut:
+			if !imdsV2 {
+				http.NotFound(w, r)
+				return
+			}
+			if r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("AQAEAtoken=="))
+		case r.URL.Path == "/latest/dynamic/instance-identity/document":
+			if imdsV2 && r.Header.Get("X-aws-ec2-metadata-token") != "AQAEAtoken==" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(testIdentityDocument))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	old := awsIMDSURL
+	awsIMDSURL = srv.URL + "/latest"
+	t.Cleanup(func() { awsIMDSURL = old })
+
+	return &requests
+}
+
+func TestGetAWSIdentity(t *testing.T) {
+	tests := []struct {
+		name string
+		dmi  map[string]string
+		want bool
+	}{
+		{"nitro", map[string]string{"sys_vendor": "Amazon EC2\n"}, true},
+		{"xen", map[string]string{"sys_vendor": "Xen\n", "bios_version": "4.11.amazon\n"}, true},
+		{"not ec2", map[string]string{"sys_vendor": "QEMU\n"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withAWSIMDS(t, true)
+			withDMI(t, tt.dmi)
+
+			ai := getAWSIdentity(newConfig(WithTimeout(time.Second)))
+			if (ai != nil) != tt.want {
+				t.Fatalf("getAWSIdentity() = %+v, want identity %v", ai, tt.want)
+			}
+
+			if !tt.want {
+				return
+			}
+
+			if ai.accountID() != "123456789012" || ai.instanceID() != "i-0a1b2c3d4e5f67890" || ai.region() != "us-east-1" {
+				t.Errorf("getAWSIdentity() = %+v, want the identity document", ai)
+			}
+
+			if got := getCloudProvider(); got != cloudAWS {
+				t.Errorf("getCloudProvider() = %q, want %q", got, cloudAWS)
+			}
+
+			if got := getCloudInstanceID(nil, nil, ai); got != "i-0a1b2c3d4e5f67890" {
+				t.Errorf("getCloudInstanceID() = %q, want the EC2 instance ID", got)
+			}
+		})
+	}
+}
+
+func TestGetAWSIdentityIMDSv1(t *testing.T) {
+	withAWSIMDS(t, false)
+	withDMI(t, map[string]string{"sys_vendor": "Amazon EC2\n"})
+
+	if ai := getAWSIdentity(newConfig(WithTimeout(time.Second))); ai.accountID() != "123456789012" {
+		t.Errorf("getAWSIdentity() = %+v, want the identity document", ai)
+	}
+
+	if ai := getAWSIdentity(newConfig(WithoutNetwork())); ai != nil {
+		t.Errorf("getAWSIdentity() = %+v without network, want nil", ai)
+	}
+}
+
+func TestGetAWSIdentitySlowIMDS(t *testing.T) {
+	// The token request gives up early, but the document alone then outlasts
+	// the rest of the attempt's timeout.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay := 450 * time.Millisecond
+		if r.Method == http.MethodPut {
+			delay = 300 * time.Millisecond
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			w.Write([]byte("AQAEAtoken=="))
+			return
+		}
+		w.Write([]byte(testIdentityDocument))
+	}))
+	t.Cleanup(srv.Close)
+
+	old := awsIMDSURL
+	awsIMDSURL = srv.URL + "/latest"
+	t.Cleanup(func() { awsIMDSURL = old })
+
+	withDMI(t, map[string]string{"sys_vendor": "Amazon EC2\n"})
+
+	if ai := getAWSIdentity(newConfig(WithTimeout(500 * time.Millisecond))); ai != nil {
+		t.Errorf("getAWSIdentity() = %+v, want nil once the attempt's timeout has passed", ai)
+	}
+}
+
+func TestGetAWSIdentityTokenHangs(t *testing.T) {
+	// With a response hop limit of 1 the token PUT from a container is never
+	// answered; the IMDSv1 document request must still get its turn.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte(testIdentityDocument))
+	}))
+	t.Cleanup(srv.Close)
+
+	old := awsIMDSURL
+	awsIMDSURL = srv.URL + "/latest"
+	t.Cleanup(func() { awsIMDSURL = old })
+
+	withDMI(t, map[string]string{"sys_vendor": "Amazon EC2\n"})
+
+	ai := getAWSIdentity(newConfig(WithTimeout(500 * time.Millisecond)))
+	if ai.instanceID() != "i-0a1b2c3d4e5f67890" {
+		t.Errorf("getAWSIdentity() = %+v, want the IMDSv1 identity document", ai)
+	}
+}
+
+func TestNewSkipsAWSIdentity(t *testing.T) {
+	requests := withAWSIMDS(t, true)
+	withDMI(t, map[string]string{"sys_vendor": "Amazon EC2\n"})
+	withOverrides(t)
+	withEnvironment(t, map[string]string{overrideScheduler: "nomad"})
+
+	NewWithOptions(WithTimeout(time.Second))
+	if n := atomic.LoadInt32(requests); n != 0 {
+		t.Errorf("New() made %d IMDS requests, want none", n)
+	}
+
+	Profile(context.Background(), WithTimeout(time.Second))
+	if n := atomic.LoadInt32(requests); n == 0 {
+		t.Error("Profile() made no IMDS requests")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// azureAssetTag is the DMI chassis asset tag Azure sets on its virtual
+// machines. The system vendor, Microsoft Corporation, is shared with on-premises
+// Hyper-V guests and Surface hardware.
+const azureAssetTag = "7783-7084-3265-9085-8269-3286-77"
+
+// azureIMDSURL is the Azure Instance Metadata Service compute endpoint.
+var azureIMDSURL = "http://169.254.169.254/metadata/instance/compute?api-version=2021-02-01"
+
+// azureCompute is the subset of the Azure IMDS compute metadata used for
+// detection.
+type azureCompute struct {
+	Location          string `json:"location"`
+	ResourceGroupName string `json:"resourceGroupName"`
+	SubscriptionID    string `json:"subscriptionId"`
+	VMID              string `json:"vmId"`
+	TagsList          []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"tagsList"`
+}
+
+// isAzure returns true if running on an Azure VM, as identified by its DMI
+// chassis asset tag.
+func isAzure() bool {
+	v, err := readDMI("chassis_asset_tag")
+	return err == nil && v == azureAssetTag
+}
+
+// getAzureCompute returns the Azure IMDS compute metadata, or nil if not
+// running on an Azure VM. The metadata service is only queried when the DMI
+// chassis asset tag identifies Azure, so other hosts, including Hyper-V guests
+// elsewhere, are never probed.
+func getAzureCompute(c *config) *azureCompute {
+	if !c.network || !isAzure() {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, azureIMDSURL, nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Metadata", "true")
+
+	var compute *azureCompute
+
+	client := &http.Client{Timeout: c.probeTimeout()}
+	c.probe(func() bool {
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return false
+		}
+
+		var ac azureCompute
+		if err := json.NewDecoder(resp.Body).Decode(&ac); err != nil {
+			return false
+		}
+
+		compute = &ac
+		return true
+	})
+
+	return compute
+}
+
+// isAKS returns true if the Azure VM is an AKS node. AKS places nodes in a
+// managed resource group named MC_<group>_<cluster>_<region> and tags them
+// with aks-managed-* tags.
+func (ac *azureCompute) isAKS() bool {
+	if ac == nil {
+		return false
+	}
+
+	if strings.HasPrefix(strings.ToUpper(ac.ResourceGroupName), "MC_") {
+		return true
+	}
+
+	for _, tag := range ac.TagsList {
+		if strings.HasPrefix(tag.Name, "aks-managed-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// subscriptionID returns the Azure subscription ID, or "" if ac is nil.
+func (ac *azureCompute) subscriptionID() string {
+	if ac == nil {
+		return ""
+	}
+
+	return ac.SubscriptionID
+}
@@ -0,0 +1,131 @@
+package criprof
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// withAzureIMDS serves body from a fake Azure IMDS compute endpoint on an
+// Azure VM for the duration of the test.
+func withAzureIMDS(t *testing.T, body string) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	old := azureIMDSURL
+	azureIMDSURL = srv.URL
+	t.Cleanup(func() { azureIMDSURL = old })
+
+	withDMI(t, map[string]string{
+		"sys_vendor":        "Microsoft Corporation\n",
+		"chassis_asset_tag": azureAssetTag + "\n",
+	})
+}
+
+func TestGetAzureComputeAKS(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		aks  bool
+	}{
+		{"managed resource group", `{"resourceGroupName":"MC_prod_cluster1_eastus","tagsList":[]}`, true},
+		{"aks tags", `{"resourceGroupName":"nodes","tagsList":[{"name":"aks-managed-poolName","value":"system"}]}`, true},
+		{"plain vm", `{"resourceGroupName":"web","tagsList":[{"name":"env","value":"prod"}]}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withAzureIMDS(t, tt.body)
+
+			az := getAzureCompute(newConfig(WithTimeout(time.Second)))
+			if az == nil {
+				t.Fatal("getAzureCompute() = nil on an Azure VM")
+			}
+
+			if got := az.isAKS(); got != tt.aks {
+				t.Errorf("isAKS() = %v, want %v", got, tt.aks)
+			}
+
+			if got := getCloudProvider(); got != cloudAzure {
+				t.Errorf("getCloudProvider() = %q, want %q", got, cloudAzure)
+			}
+
+			want := ""
+			if tt.aks {
+				want = flavorAKS
+			}
+			if got := getSchedulerFlavor(schedulerUndetermined, az); got != want {
+				t.Errorf("getSchedulerFlavor() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestGetAzureComputeIdentity(t *testing.T) {
+	withAzureIMDS(t, `{
+		"location": "westeurope",
+		"name": "web-vm-1",
+		"resourceGroupName": "web",
+		"subscriptionId": "8d3c1f2e-5a4b-4c6d-9e8f-7a6b5c4d3e2f",
+		"tagsList": [],
+		"vmId": "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+		"vmSize": "Standard_D2s_v3"
+	}`)
+
+	az := getAzureCompute(newConfig(WithTimeout(time.Second)))
+
+	if got := az.subscriptionID(); got != "8d3c1f2e-5a4b-4c6d-9e8f-7a6b5c4d3e2f" {
+		t.Errorf("subscriptionID() = %q, want the compute subscriptionId", got)
+	}
+
+	if got := getCloudInstanceID(az, nil, nil); got != "02aab8a4-74ef-476e-8182-f6d2ba4166a6" {
+		t.Errorf("getCloudInstanceID() = %q, want the vmId", got)
+	}
+
+	if got := getCloudRegion(az, nil); got != "westeurope" {
+		t.Errorf("getCloudRegion() = %q, want westeurope", got)
+	}
+}
+
+func TestGetCloudInstanceIDEmptyVMID(t *testing.T) {
+	az := &azureCompute{Location: "westeurope"}
+	gcp := &gcpIdentity{project: "acme-prod", instance: "4520691263283021476"}
+
+	if got := getCloudInstanceID(az, gcp, nil); got != "4520691263283021476" {
+		t.Errorf("getCloudInstanceID() = %q, want the GCP instance ID when vmId is empty", got)
+	}
+}
+
+func TestGetAzureComputeNotAzure(t *testing.T) {
+	withAzureIMDS(t, `{"resourceGroupName":"MC_prod_cluster1_eastus"}`)
+	withDMI(t, map[string]string{"sys_vendor": "Amazon EC2\n"})
+
+	if az := getAzureCompute(newConfig()); az != nil {
+		t.Errorf("getAzureCompute() = %+v off Azure, want nil", az)
+	}
+
+	// An on-premises Hyper-V guest shares Azure's system vendor.
+	withDMI(t, map[string]string{
+		"sys_vendor":        "Microsoft Corporation\n",
+		"chassis_asset_tag": "0000-0000-0000-0000-0000-0000-00\n",
+	})
+
+	if az := getAzureCompute(newConfig()); az != nil {
+		t.Errorf("getAzureCompute() = %+v on Hyper-V, want nil", az)
+	}
+
+	withDMI(t, map[string]string{"chassis_asset_tag": azureAssetTag + "\n"})
+
+	if az := getAzureCompute(newConfig(WithoutNetwork())); az != nil {
+		t.Errorf("getAzureCompute() = %+v with network disabled, want nil", az)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// cgroupPaths are the cgroup membership files inspected, in order, unless
+// replaced by WithCgroupPaths. The process's own cgroup is checked first; PID
+// 1's cgroup covers runtimes such as Mesos that only mark the container's
+// init, and processes that are PID 1.
+var cgroupPaths = []string{
+	"/proc/self/cgroup",
+	"/proc/1/cgroup",
+}
+
+// readCgroup returns the concatenated contents of every readable file in the
+// configured cgroup paths so each detector matches against the same data.
+// Under hostPID, PID 1 belongs to the host, so only the first path is read.
+func readCgroup(c *config) string {
+	var b strings.Builder
+
+	hostPID := isHostPID()
+
+	for i, p := range c.cgroupPaths {
+		if hostPID && i > 0 {
+			break
+		}
+
+		cgroup, err := ioutil.ReadFile(p)
+		if err != nil || len(cgroup) == 0 {
+			continue
+		}
+
+		b.Write(cgroup)
+		if cgroup[len(cgroup)-1] != '\n' {
+			b.WriteByte('\n')
+		}
+	}
+
+	return b.String()
+}
+
+// parseCgroupRuntime returns the runtime named in cgroup file contents read
+// from r, or "" if none is recognised.
+func parseCgroupRuntime(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), "docker") {
+			return runtimeDocker
+		}
+	}
+
+	return ""
+}
+
+// containerScopeMatch matches a cgroup path segment naming a container by its
+// full ID, bare as with cgroupfs or wrapped as a systemd scope such as
+// docker-<id>.scope or cri-containerd-<id>.scope.
+var containerScopeMatch = regexp.MustCompile(`^(?:[a-z-]+[-:])?[0-9a-f]{64}(?:\.scope)?$`)
+
+// getCgroupDepth returns how many containers the process's own cgroup path
+// passes through, or 0 if none can be seen, as under a private cgroup
+// namespace. A depth above 1 indicates a container nested in another, such as
+// Docker-in-Docker or a kind node's pods.
+func getCgroupDepth(c *config) int {
+	if len(c.cgroupPaths) == 0 {
+		return 0
+	}
+
+	f, err := os.Open(c.cgroupPaths[0])
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	return parseCgroupDepth(f)
+}
+
+// parseCgroupDepth returns the greatest number of container path segments in
+// any hierarchy of cgroup file contents read from r.
+func parseCgroupDepth(r io.Reader) int {
+	var depth int
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		var n int
+		for _, seg := range strings.Split(fields[2], "/") {
+			if containerScopeMatch.MatchString(seg) {
+				n++
+			}
+		}
+
+		if n > depth {
+			depth = n
+		}
+	}
+
+	return depth
+}
+
+// cgroupControlPaths are the cgroup v2 interface files a process writes to
+// move processes and adjust limits. They are only inspected, never written.
+var cgroupControlPaths = []string{
+	"/sys/fs/cgroup/cgroup.procs",
+	"/sys/fs/cgroup/memory.max",
+}
+
+// isCgroupWritable returns true if the process may write to its cgroup's
+// control files, as when cgroup control has been delegated to the container.
+// Writability is judged from the mode bits, owner and mount options against
+// the effective UID; nothing is written.
+func isCgroupWritable() bool {
+	entries, _ := readMountInfo()
+
+	return cgroupWritableFrom(cgroupControlPaths, os.Stat, entries, os.Geteuid())
+}
+
+// cgroupWritableFrom returns true if at least one of paths exists and every
+// one that does is writable by euid and not on a read-only mount in entries.
+// Root bypasses the mode bits but not a read-only mount, as Docker and
+// Kubernetes give /sys/fs/cgroup.
+func cgroupWritableFrom(paths []string, stat func(string) (os.FileInfo, error), entries []mountEntry, euid int) bool {
+	found := false
+
+	for _, p := range paths {
+		fi, err := stat(p)
+		if err != nil {
+			continue
+		}
+		found = true
+
+		owner, ok := fileOwner(fi)
+		if !canWrite(fi.Mode(), owner, ok, euid) {
+			return false
+		}
+
+		if m, ok := findMount(entries, p); ok && hasMountOption(m.Options, "ro") {
+			return false
+		}
+	}
+
+	return found
+}
+
+// canWrite reports whether euid may write a file with the given mode and
+// owner. Group membership is not considered.
+func canWrite(mode os.FileMode, owner int, ownerKnown bool, euid int) bool {
+	perm := mode.Perm()
+
+	switch {
+	case euid == 0:
+		return perm&0o222 != 0
+	case ownerKnown && owner == euid:
+		return perm&0o200 != 0
+	}
+
+	return perm&0o002 != 0
+}
+
+// CPU weight files for cgroup v2 and v1.
+var (
+	cpuWeightPath = "/sys/fs/cgroup/cpu.weight"
+	cpuSharesPath = "/sys/fs/cgroup/cpu/cpu.shares"
+)
+
+// getCPUShares returns the container's relative CPU priority on both cgroup
+// scales: v1 cpu.shares (2-262144, default 1024) and v2 cpu.weight (1-10000,
+// default 100). Whichever file is present is read and the other value is
+// converted from it, as runc and the kubelet do:
+//
+//	weight = 1 + (shares-2)*9999/262142
+//	shares = 2 + (weight-1)*262142/9999
+//
+// Both are 0 if neither file can be read.
+func getCPUShares() (int64, int64) {
+	if weight := readCgroupInt(cpuWeightPath); weight > 0 {
+		return cpuWeightToShares(weight), weight
+	}
+
+	if shares := readCgroupInt(cpuSharesPath); shares > 0 {
+		return shares, cpuSharesToWeight(shares)
+	}
+
+	return 0, 0
+}
+
+// cpuSharesToWeight converts cgroup v1 cpu.shares to a v2 cpu.weight.
+func cpuSharesToWeight(shares int64) int64 {
+	return 1 + (shares-2)*9999/262142
+}
+
+// cpuWeightToShares converts a cgroup v2 cpu.weight to v1 cpu.shares.
+func cpuWeightToShares(weight int64) int64 {
+	return 2 + (weight-1)*262142/9999
+}
+
+// readCgroupInt returns the integer in the cgroup interface file at p, or 0 if
+// it cannot be read or parsed.
+func readCgroupInt(p string) int64 {
+	v, err := ioutil.ReadFile(p)
+	if err != nil {
+		return 0
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(string(v)), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
+
+// pidsMaxPaths are the pids controller limit files for cgroup v2 and v1, in
+// order.
+var pidsMaxPaths = []string{
+	"/sys/fs/cgroup/pids.max",
+	"/sys/fs/cgroup/pids/pids.max",
+}
+
+// getPidsLimit returns the maximum number of processes the container's pids
+// cgroup allows, or 0 if it is unlimited or cannot be read.
+func getPidsLimit() int64 {
+	for _, p := range pidsMaxPaths {
+		v, err := ioutil.ReadFile(p)
+		if err != nil {
+			continue
+		}
+
+		return parsePidsMax(string(v))
+	}
+
+	return 0
+}
+
+// parsePidsMax parses the contents of pids.max, in which "max" means no limit.
+func parsePidsMax(v string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+
+	return n
+}
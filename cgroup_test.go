@@ -0,0 +1,357 @@
+package criprof
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withCgroupFiles points cgroupPaths at temporary files holding the given
+// contents for the duration of the test. An empty string leaves the file
+// absent.
+func withCgroupFiles(t *testing.T, contents ...string) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	var paths []string
+	for i, c := range contents {
+		p := filepath.Join(dir, "cgroup"+string(rune('0'+i)))
+		if c != "" {
+			if err := ioutil.WriteFile(p, []byte(c), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		paths = append(paths, p)
+	}
+
+	old := cgroupPaths
+	cgroupPaths = paths
+	t.Cleanup(func() { cgroupPaths = old })
+}
+
+func TestReadCgroupFallsBackToPID1(t *testing.T) {
+	withCgroupFiles(t,
+		"0::/\n",
+		"3:cpu:/docker/4f3a9c2b1d0e\n2:cpuset:/mesos/1a2b\n",
+	)
+
+	cgroup := readCgroup(newConfig())
+	if !strings.Contains(cgroup, "mesos") {
+		t.Errorf("readCgroup() = %q, want PID 1's cgroup included", cgroup)
+	}
+
+	if got := getContainerID(newConfig()); got != "4f3a9c2b1d0e" {
+		t.Errorf("getContainerID() = %q, want %q", got, "4f3a9c2b1d0e")
+	}
+}
+
+func TestReadCgroupHostPID(t *testing.T) {
+	withCgroupFiles(t,
+		"0::/\n",
+		"0::/init.scope\n",
+	)
+	dir := withProcTree(t,
+		testProcess{"4211", "0", "app"},
+		testProcess{"1", "0", "systemd"},
+	)
+	writeTestNamespace(t, dir, "4211/ns/mnt", "mnt:[4026532282]")
+	writeTestNamespace(t, dir, "1/ns/mnt", "mnt:[4026531841]")
+
+	if got := readCgroup(newConfig()); got != "0::/\n" {
+		t.Errorf("readCgroup() = %q, want only the process's own cgroup", got)
+	}
+}
+
+func TestReadCgroupMissing(t *testing.T) {
+	withCgroupFiles(t, "", "")
+
+	if got := readCgroup(newConfig()); got != "" {
+		t.Errorf("readCgroup() = %q, want empty", got)
+	}
+
+	if got := getContainerID(newConfig()); got != "undetermined" {
+		t.Errorf("getContainerID() = %q, want %q", got, "undetermined")
+	}
+}
+
+func TestWithCgroupPaths(t *testing.T) {
+	withCgroupFiles(t, "0::/\n")
+
+	p := filepath.Join(t.TempDir(), "cgroup")
+	if err := ioutil.WriteFile(p, []byte("3:cpu:/docker/4f3a9c2b1d0e\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := getContainerID(newConfig(WithCgroupPaths(p))); got != "4f3a9c2b1d0e" {
+		t.Errorf("getContainerID() = %q, want the ID from the configured cgroup path", got)
+	}
+
+	if got := getContainerID(newConfig()); got != "undetermined" {
+		t.Errorf("getContainerID() = %q, want the default paths left unchanged", got)
+	}
+}
+
+func TestParseContainerID(t *testing.T) {
+	const id64 = "4f3a9c2b1d0e8b3e2c5a9f1d4e7b6a5c3d2e1f0a9b8c7d6e5f4a3b2c1d0e9f8a"
+
+	tests := []struct {
+		name       string
+		cgroup     string
+		want       string
+		wantSource string
+	}{
+		{"docker", "4:cpuset:/\n3:cpu:/docker/4f3a9c2b1d0e\n", "4f3a9c2b1d0e", idSourceDockerV1},
+		{"coreos", "4:cpuset:/system.slice/docker-" + id64 + ".scope\n1:name=systemd:/system.slice/docker.service\n", id64, idSourceCoreOSV1},
+		{"docker before coreos", "4:cpuset:/system.slice/docker-" + id64 + ".scope\n3:cpu:/docker/4f3a9c2b1d0e\n", "4f3a9c2b1d0e", idSourceDockerV1},
+		{"malformed docker", "3:cpu:/docker/4f3a9c2b1d\n", "undetermined", ""},
+		{"malformed docker falls through", "3:cpu:/docker/4f3a9c2b1d\n4:cpuset:/system.slice/docker-" + id64 + ".scope\n", id64, idSourceCoreOSV1},
+		{"uppercase", "3:cpu:/docker/4F3A9C2B1D0E\n", "undetermined", ""},
+		{"docker v2", "0::/system.slice/docker-" + id64 + ".scope\n", id64, idSourceDockerV2},
+		{"cri-o v2", "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod7c1e.slice/crio-" + id64 + ".scope\n", id64, idSourceCRIOV2},
+		{"containerd v2", "0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod7c1e.slice/cri-containerd-" + id64 + ".scope\n", id64, idSourceContainerdV2},
+		{"cgroup v2 root", "0::/\n", "undetermined", ""},
+		{"empty", "", "undetermined", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source := parseContainerID(strings.NewReader(tt.cgroup))
+			if got != tt.want || source != tt.wantSource {
+				t.Errorf("parseContainerID() = %q, %q, want %q, %q", got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestShortContainerID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"4f3a9c2b1d0e8b3e2c5a9f1d4e7b6a5c3d2e1f0a9b8c7d6e5f4a3b2c1d0e9f8a", "4f3a9c2b1d0e"},
+		{"4f3a9c2b1d0e", "4f3a9c2b1d0e"},
+		{"undetermined", ""},
+	}
+
+	for _, tt := range tests {
+		if got := shortContainerID(tt.id); got != tt.want {
+			t.Errorf("shortContainerID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestParseCgroupRuntime(t *testing.T) {
+	if got := parseCgroupRuntime(strings.NewReader("12:pids:/docker/4f3a9c2b1d0e\n")); got != runtimeDocker {
+		t.Errorf("parseCgroupRuntime() = %q, want %q", got, runtimeDocker)
+	}
+
+	if got := parseCgroupRuntime(strings.NewReader("0::/user.slice\n")); got != "" {
+		t.Errorf("parseCgroupRuntime() = %q, want empty", got)
+	}
+}
+
+func BenchmarkGetContainerID(b *testing.B) {
+	b.ReportAllocs()
+
+	// Run getContainerID function b.N times.
+	for i := 0; i < b.N; i++ {
+		getContainerID(newConfig())
+	}
+}
+
+func TestCanWrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  os.FileMode
+		owner int
+		euid  int
+		want  bool
+	}{
+		{"root writable", 0o644, 0, 0, true},
+		{"root read-only", 0o444, 0, 0, false},
+		{"delegated owner", 0o644, 1000, 1000, true},
+		{"other user", 0o644, 0, 1000, false},
+		{"world writable", 0o666, 0, 1000, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canWrite(tt.mode, tt.owner, true, tt.euid); got != tt.want {
+				t.Errorf("canWrite(%v, %d, %d) = %v, want %v", tt.mode, tt.owner, tt.euid, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCgroupWritable(t *testing.T) {
+	dir := t.TempDir()
+	procs := writeTestFile(t, dir, "cgroup.procs", "")
+	memory := writeTestFile(t, dir, "memory.max", "max\n")
+
+	old := cgroupControlPaths
+	cgroupControlPaths = []string{procs, memory}
+	t.Cleanup(func() { cgroupControlPaths = old })
+
+	if !isCgroupWritable() {
+		t.Error("isCgroupWritable() = false for owner-writable files")
+	}
+
+	if err := os.Chmod(memory, 0o444); err != nil {
+		t.Fatal(err)
+	}
+
+	if isCgroupWritable() {
+		t.Error("isCgroupWritable() = true with a read-only memory.max")
+	}
+
+	cgroupControlPaths = []string{filepath.Join(dir, "missing")}
+	if isCgroupWritable() {
+		t.Error("isCgroupWritable() = true without cgroup files")
+	}
+}
+
+func TestCgroupWritableFrom(t *testing.T) {
+	const root = "1197 1103 0:113 / / rw,relatime - overlay overlay rw\n"
+
+	readOnly, err := parseMountInfo(strings.NewReader(root +
+		"1205 1197 0:30 / /sys/fs/cgroup ro,nosuid,nodev,noexec,relatime - cgroup2 cgroup rw\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readWrite, err := parseMountInfo(strings.NewReader(root +
+		"1205 1197 0:30 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime - cgroup2 cgroup rw\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stat := func(p string) (os.FileInfo, error) {
+		return testDirInfo{name: p, mode: 0o644}, nil
+	}
+
+	if cgroupWritableFrom(cgroupControlPaths, stat, readOnly, 0) {
+		t.Error("cgroupWritableFrom() = true for root on a read-only cgroup2 mount")
+	}
+
+	if !cgroupWritableFrom(cgroupControlPaths, stat, readWrite, 0) {
+		t.Error("cgroupWritableFrom() = false for root on a read-write cgroup2 mount")
+	}
+}
+
+func TestResolveContainerIDFromHostname(t *testing.T) {
+	withCgroupFiles(t, "0::/\n", "")
+
+	id, source, note := resolveContainerID(newConfig(), "4f3a9c2b1d0e")
+	if id != "4f3a9c2b1d0e" || source != idSourceHostname || note == "" {
+		t.Errorf("resolveContainerID(short id) = %q, %q, %q, want hostname with a note", id, source, note)
+	}
+
+	id, source, note = resolveContainerID(newConfig(), "web-1")
+	if id != "undetermined" || source != "" || note != "" {
+		t.Errorf("resolveContainerID(web-1) = %q, %q, %q, want undetermined without a note", id, source, note)
+	}
+
+	withCgroupFiles(t, "3:cpu:/docker/8b3e2c5a9f1d\n")
+
+	id, source, note = resolveContainerID(newConfig(), "4f3a9c2b1d0e")
+	if id != "8b3e2c5a9f1d" || source != idSourceDockerV1 || note != "" {
+		t.Errorf("resolveContainerID() = %q, %q, %q, want the cgroup ID without a note", id, source, note)
+	}
+}
+
+func TestGetPidsLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  int64
+	}{
+		{"v2 limit", map[string]string{"v2": "4096\n"}, 4096},
+		{"v2 unlimited", map[string]string{"v2": "max\n"}, 0},
+		{"v1 limit", map[string]string{"v1": "1024\n"}, 1024},
+		{"absent", map[string]string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, contents := range tt.files {
+				writeTestFile(t, dir, name, contents)
+			}
+
+			old := pidsMaxPaths
+			pidsMaxPaths = []string{filepath.Join(dir, "v2"), filepath.Join(dir, "v1")}
+			t.Cleanup(func() { pidsMaxPaths = old })
+
+			if got := getPidsLimit(); got != tt.want {
+				t.Errorf("getPidsLimit() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCPUShares(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		wantShares int64
+		wantWeight int64
+	}{
+		{"v2 default", map[string]string{"cpu.weight": "100\n"}, 2597, 100},
+		{"v2 weight", map[string]string{"cpu.weight": "39\n"}, 998, 39},
+		{"v1 default", map[string]string{"cpu.shares": "1024\n"}, 1024, 39},
+		{"v1 minimum", map[string]string{"cpu.shares": "2\n"}, 2, 1},
+		{"v1 maximum", map[string]string{"cpu.shares": "262144\n"}, 262144, 10000},
+		{"v2 preferred", map[string]string{"cpu.weight": "10000\n", "cpu.shares": "1024\n"}, 262144, 10000},
+		{"absent", map[string]string{}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, contents := range tt.files {
+				writeTestFile(t, dir, name, contents)
+			}
+
+			oldWeight, oldShares := cpuWeightPath, cpuSharesPath
+			cpuWeightPath, cpuSharesPath = filepath.Join(dir, "cpu.weight"), filepath.Join(dir, "cpu.shares")
+			t.Cleanup(func() { cpuWeightPath, cpuSharesPath = oldWeight, oldShares })
+
+			shares, weight := getCPUShares()
+			if shares != tt.wantShares || weight != tt.wantWeight {
+				t.Errorf("getCPUShares() = %d, %d, want %d, %d", shares, weight, tt.wantShares, tt.wantWeight)
+			}
+		})
+	}
+}
+
+func TestParseCgroupDepth(t *testing.T) {
+	const (
+		id1 = "9b2f0a7c1d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f90"
+		id2 = "1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f90a1b2c3d4e5f6a7b8c9d0e1f2a3"
+	)
+
+	tests := []struct {
+		name   string
+		cgroup string
+		want   int
+	}{
+		{"cgroup v1 docker", "12:memory:/docker/" + id1 + "\n1:name=systemd:/docker/" + id1 + "\n", 1},
+		{"kubepods", "0::/kubepods/burstable/pod5f0c2e8a-3b1d-4c6e-9a7f-2d8b0e1c4a6f/" + id1 + "\n", 1},
+		{"systemd scope", "0::/system.slice/docker-" + id1 + ".scope\n", 1},
+		{"docker in docker", "0::/docker/" + id1 + "/docker/" + id2 + "\n", 2},
+		{"kind pod", "0::/system.slice/docker-" + id1 + ".scope/kubelet.slice/kubelet-kubepods.slice/kubelet-kubepods-besteffort.slice/cri-containerd-" + id2 + ".scope\n", 2},
+		{"cgroup namespace", "0::/\n", 0},
+		{"host", "0::/user.slice/user-1000.slice/session-2.scope\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCgroupDepth(strings.NewReader(tt.cgroup)); got != tt.want {
+				t.Errorf("parseCgroupDepth() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
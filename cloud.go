@@ -0,0 +1,53 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+// Cloud providers reported in Inventory.CloudProvider.
+const (
+	cloudAWS   = "aws"   // Amazon Web Services
+	cloudAzure = "azure" // Microsoft Azure
+	cloudGCP   = "gcp"   // Google Cloud
+)
+
+// getCloudProvider returns the cloud provider hosting the workload, or "" if
+// unknown. It is recognised from DMI alone, so metadata services are only
+// queried by Profile.
+func getCloudProvider() string {
+	switch {
+	case isAzure():
+		return cloudAzure
+	case isEC2():
+		return cloudAWS
+	case isGCE():
+		return cloudGCP
+	}
+
+	return ""
+}
+
+// getCloudInstanceID returns the cloud provider's ID for the VM, such as an
+// Azure vmId or EC2 instance ID, or "" if unknown.
+func getCloudInstanceID(az *azureCompute, gcp *gcpIdentity, ec2 *awsIdentity) string {
+	switch {
+	case ec2.instanceID() != "":
+		return ec2.instanceID()
+	case az != nil && az.VMID != "":
+		return az.VMID
+	}
+
+	return gcp.instanceID()
+}
+
+// getCloudRegion returns the cloud region hosting the VM, or "" if unknown.
+func getCloudRegion(az *azureCompute, ec2 *awsIdentity) string {
+	if r := ec2.region(); r != "" {
+		return r
+	}
+
+	if az != nil {
+		return az.Location
+	}
+
+	return ""
+}
@@ -0,0 +1,39 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+// Sources of Inventory.ClusterName.
+const (
+	clusterSourceEnv         = "env"          // CLUSTER_NAME environment variable
+	clusterSourceGKEMetadata = "gke-metadata" // GKE node metadata attribute
+)
+
+// getClusterNameFromEnv returns the cluster name set by CLUSTER_NAME and its
+// source, or empty values if it is unset.
+func getClusterNameFromEnv() (string, string) {
+	if n := EnvironmentVariables["CLUSTER_NAME"]; n != "" {
+		return n, clusterSourceEnv
+	}
+
+	return "", ""
+}
+
+// getClusterName returns the name of the Kubernetes cluster and the source it
+// was read from, or empty values if it is not discoverable. An explicit
+// CLUSTER_NAME takes precedence over the GKE metadata server.
+func getClusterName(c *config, scheduler string) (string, string) {
+	if n, source := getClusterNameFromEnv(); n != "" {
+		return n, source
+	}
+
+	if !isKubernetesScheduler(scheduler) {
+		return "", ""
+	}
+
+	if n, ok := getGCPMetadata(c, "instance/attributes/cluster-name"); ok && n != "" {
+		return n, clusterSourceGKEMetadata
+	}
+
+	return "", ""
+}
@@ -0,0 +1,97 @@
+package criprof
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withGCPMetadata serves the given metadata paths from a fake metadata server
+// on a Compute Engine VM for the duration of the test, and returns the number
+// of requests it has served.
+func withGCPMetadata(t *testing.T, values map[string]string) *int32 {
+	t.Helper()
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		v, ok := values[r.URL.Path]
+		if !ok || r.Header.Get("Metadata-Flavor") != "Google" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(v))
+	}))
+	t.Cleanup(srv.Close)
+
+	old := gcpMetadataURL
+	gcpMetadataURL = srv.URL
+	t.Cleanup(func() { gcpMetadataURL = old })
+
+	withDMI(t, map[string]string{"product_name": gcpProduct + "\n"})
+
+	return &requests
+}
+
+func TestGetClusterName(t *testing.T) {
+	withGCPMetadata(t, map[string]string{"/instance/attributes/cluster-name": "prod-us-central1"})
+	c := newConfig(WithTimeout(time.Second))
+
+	withEnvironment(t, map[string]string{})
+
+	name, source := getClusterName(c, schedulerKubernetes)
+	if name != "prod-us-central1" || source != clusterSourceGKEMetadata {
+		t.Errorf("getClusterName() = %q, %q, want the GKE metadata attribute", name, source)
+	}
+
+	if name, _ := getClusterName(c, schedulerEKSFargate); name != "prod-us-central1" {
+		t.Errorf("getClusterName(eks-fargate) = %q, want the metadata attribute", name)
+	}
+
+	if name, _ := getClusterName(c, schedulerNomad); name != "" {
+		t.Errorf("getClusterName(nomad) = %q, want empty", name)
+	}
+
+	withEnvironment(t, map[string]string{"CLUSTER_NAME": "staging"})
+
+	name, source = getClusterName(c, schedulerKubernetes)
+	if name != "staging" || source != clusterSourceEnv {
+		t.Errorf("getClusterName() = %q, %q, want the CLUSTER_NAME variable", name, source)
+	}
+}
+
+func TestGetClusterNameUndiscoverable(t *testing.T) {
+	withGCPMetadata(t, map[string]string{})
+	withEnvironment(t, map[string]string{})
+
+	if name, source := getClusterName(newConfig(WithTimeout(time.Second)), schedulerKubernetes); name != "" || source != "" {
+		t.Errorf("getClusterName() = %q, %q, want empty", name, source)
+	}
+
+	withGCPMetadata(t, map[string]string{"/instance/attributes/cluster-name": "prod-us-central1"})
+
+	if name, _ := getClusterName(newConfig(WithoutNetwork()), schedulerKubernetes); name != "" {
+		t.Errorf("getClusterName() = %q with network disabled, want empty", name)
+	}
+}
+
+func TestNewSkipsGKEMetadata(t *testing.T) {
+	requests := withGCPMetadata(t, map[string]string{
+		"/instance/attributes/cluster-name": "prod-us-central1",
+		"/instance/service-accounts/":       "default/\nshop-prod.svc.id.goog/\n",
+	})
+	withEnvironment(t, map[string]string{overrideScheduler: schedulerKubernetes})
+
+	i := NewWithOptions(WithTimeout(time.Second), WithOverrides())
+	if n := atomic.LoadInt32(requests); n != 0 || i.ClusterName != "" || i.WorkloadIdentity != "" {
+		t.Errorf("New() made %d metadata requests and reported cluster %q, identity %q, want none", n, i.ClusterName, i.WorkloadIdentity)
+	}
+
+	p := Profile(context.Background(), WithTimeout(time.Second), WithOverrides())
+	if p.ClusterName != "prod-us-central1" || p.ClusterNameSource != clusterSourceGKEMetadata || p.WorkloadIdentity != workloadIdentityGKE {
+		t.Errorf("Profile() = cluster %q from %q, identity %q, want the GKE metadata", p.ClusterName, p.ClusterNameSource, p.WorkloadIdentity)
+	}
+}
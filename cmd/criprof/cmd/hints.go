@@ -3,25 +3,68 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/christianvozar/criprof"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	hintsTimeout   time.Duration
+	hintsNoNetwork bool
+	hintsFormat    string
+)
+
+// newInventory builds the inventory displayed by the hints command.
+var newInventory = criprof.NewWithContext
+
 // hintsCmd represents the hints command
 var hintsCmd = &cobra.Command{
 	Use:   "hints",
 	Short: "Display container runtime information",
 	Long:  `Display container runtime information`,
-	Run: func(cmd *cobra.Command, args []string) {
-		i := criprof.New()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		var opts []criprof.Option
+
+		// Bound the whole run, reporting what was gathered by the deadline.
+		if hintsTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, hintsTimeout)
+			defer cancel()
+
+			opts = append(opts, criprof.WithPartialResults())
+		}
 
-		fmt.Println(i.JSON())
+		if hintsNoNetwork {
+			opts = append(opts, criprof.WithoutNetwork())
+		}
+
+		i, err := newInventory(ctx, opts...)
+		if err != nil {
+			return err
+		}
+
+		switch hintsFormat {
+		case "json":
+			fmt.Fprintln(cmd.OutOrStdout(), i.JSON())
+		case "logfmt":
+			fmt.Fprintln(cmd.OutOrStdout(), i.Logfmt())
+		default:
+			return fmt.Errorf("unknown format %q: must be json or logfmt", hintsFormat)
+		}
+
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(hintsCmd)
+
+	hintsCmd.Flags().DurationVar(&hintsTimeout, "timeout", 0, "deadline for the whole run; detection still pending is reported as partial")
+	hintsCmd.Flags().StringVar(&hintsFormat, "format", "json", "output format: json or logfmt")
+	hintsCmd.Flags().BoolVar(&hintsNoNetwork, "no-network", false, "skip detection that performs network I/O")
 }
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/christianvozar/criprof"
+)
+
+func TestHintsTimeout(t *testing.T) {
+	old := newInventory
+	newInventory = func(ctx context.Context, opts ...criprof.Option) (*criprof.Inventory, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("hints --timeout ran detection without a deadline")
+		}
+
+		// Stand in for probes that outlast the deadline.
+		<-ctx.Done()
+		return criprof.NewWithContext(ctx, append(opts, criprof.WithoutNetwork())...)
+	}
+	t.Cleanup(func() {
+		newInventory = old
+		hintsTimeout = 0
+	})
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+
+	start := time.Now()
+	rootCmd.SetArgs([]string{"hints", "--timeout", "50ms"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v, want the partial inventory", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("hints --timeout 50ms ran for %v", elapsed)
+	}
+
+	if !strings.Contains(out.String(), `"partial":true`) {
+		t.Errorf("hints output = %q, want a partial inventory", out.String())
+	}
+}
+
+func TestHintsFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		deadline bool
+		wantOpts int
+	}{
+		{"defaults", []string{"hints"}, false, 0},
+		{"no network", []string{"hints", "--no-network"}, false, 1},
+		{"timeout and no network", []string{"hints", "--timeout", "1s", "--no-network"}, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotOpts int
+			var gotDeadline bool
+
+			old := newInventory
+			newInventory = func(ctx context.Context, opts ...criprof.Option) (*criprof.Inventory, error) {
+				_, gotDeadline = ctx.Deadline()
+				gotOpts = len(opts)
+				return &criprof.Inventory{}, nil
+			}
+			t.Cleanup(func() {
+				newInventory = old
+				hintsTimeout = 0
+				hintsNoNetwork = false
+			})
+
+			rootCmd.SetArgs(tt.args)
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if gotDeadline != tt.deadline || gotOpts != tt.wantOpts {
+				t.Errorf("hints ran with deadline %v and %d options, want %v and %d", gotDeadline, gotOpts, tt.deadline, tt.wantOpts)
+			}
+		})
+	}
+}
+
+func TestHintsOutput(t *testing.T) {
+	old := newInventory
+	newInventory = func(ctx context.Context, opts ...criprof.Option) (*criprof.Inventory, error) {
+		return &criprof.Inventory{Hostname: "web-1", Runtime: "docker"}, nil
+	}
+	t.Cleanup(func() { newInventory = old })
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+
+	rootCmd.SetArgs([]string{"hints"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := (&criprof.Inventory{Hostname: "web-1", Runtime: "docker"}).JSON() + "\n"
+	if got := out.String(); got != want {
+		t.Errorf("hints output = %q, want %q", got, want)
+	}
+}
+
+func TestHintsFormatLogfmt(t *testing.T) {
+	inv := &criprof.Inventory{Hostname: "web 1", Runtime: "docker"}
+
+	old := newInventory
+	newInventory = func(ctx context.Context, opts ...criprof.Option) (*criprof.Inventory, error) { return inv, nil }
+	t.Cleanup(func() {
+		newInventory = old
+		hintsFormat = "json"
+	})
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+
+	rootCmd.SetArgs([]string{"hints", "--format", "logfmt"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if got, want := out.String(), inv.Logfmt()+"\n"; got != want {
+		t.Errorf("hints output = %q, want %q", got, want)
+	}
+}
+
+func TestVersionOutput(t *testing.T) {
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+
+	rootCmd.SetArgs([]string{"version"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if got := out.String(); got != "criprof version 1.1\n" {
+		t.Errorf("version output = %q, want %q", got, "criprof version 1.1\n")
+	}
+}
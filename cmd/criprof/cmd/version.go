@@ -14,7 +14,7 @@ var versionCmd = &cobra.Command{
 	Short: "Print version information",
 	Long:  `Print version information`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("criprof version 1.1")
+		fmt.Fprintln(cmd.OutOrStdout(), "criprof version 1.1")
 	},
 }
 
@@ -0,0 +1,95 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// Detectable CNI plugins.
+const (
+	cniCilium  = "cilium"  // Cilium
+	cniCalico  = "calico"  // Project Calico
+	cniFlannel = "flannel" // Flannel
+	cniWeave   = "weave"   // Weave Net
+)
+
+// cniConfigPaths are the CNI network configuration directories checked, in
+// order. /host/etc is the conventional hostPath mount for node agents.
+var cniConfigPaths = []string{
+	"/host/etc/cni/net.d",
+	"/etc/cni/net.d",
+}
+
+// cniMarkers maps the substrings that identify each CNI plugin's configuration
+// files and interfaces, checked in order.
+var cniMarkers = []struct {
+	cni        string
+	config     string
+	interfaces []string
+}{
+	{cniCilium, "cilium", []string{"cilium_"}},
+	{cniCalico, "calico", []string{"cali", "tunl0"}},
+	{cniFlannel, "flannel", []string{"flannel."}},
+	{cniWeave, "weave", []string{"weave"}},
+}
+
+// getCNI returns the Kubernetes CNI plugin in use, or "" if unknown. The
+// configuration directory and plugin interfaces are only visible to pods with
+// host mounts or host networking.
+func getCNI() string {
+	for _, dir := range cniConfigPaths {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+
+		if c := cniFromConfigs(names); c != "" {
+			return c
+		}
+	}
+
+	names, err := interfaceNames()
+	if err != nil {
+		return ""
+	}
+
+	return cniFromInterfaces(names)
+}
+
+// cniFromConfigs returns the CNI plugin named by the CNI configuration file
+// names, such as 05-cilium.conflist. The runtime uses the lexically first
+// configuration, which ReadDir returns first.
+func cniFromConfigs(names []string) string {
+	for _, n := range names {
+		for _, m := range cniMarkers {
+			if strings.Contains(n, m.config) {
+				return m.cni
+			}
+		}
+	}
+
+	return ""
+}
+
+// cniFromInterfaces returns the CNI plugin owning any of the interface names.
+func cniFromInterfaces(names []string) string {
+	for _, m := range cniMarkers {
+		for _, n := range names {
+			for _, prefix := range m.interfaces {
+				if strings.HasPrefix(n, prefix) {
+					return m.cni
+				}
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,40 @@
+package criprof
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGetCNI(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []string
+		ifaces  []string
+		want    string
+	}{
+		{"cilium config", []string{"05-cilium.conflist"}, []string{"lo", "eth0"}, cniCilium},
+		{"calico config", []string{"10-calico.conflist", "calico-kubeconfig"}, []string{"lo", "eth0"}, cniCalico},
+		{"cilium interfaces", nil, []string{"lo", "ens5", "cilium_host", "cilium_net", "lxc4f3a9c2b"}, cniCilium},
+		{"calico interfaces", nil, []string{"lo", "ens5", "tunl0", "cali7c1e2f3a4b5"}, cniCalico},
+		{"pod network", nil, []string{"lo", "eth0"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, c := range tt.configs {
+				writeTestFile(t, dir, c, "{}\n")
+			}
+
+			old := cniConfigPaths
+			cniConfigPaths = []string{dir, filepath.Join(dir, "missing")}
+			t.Cleanup(func() { cniConfigPaths = old })
+
+			withInterfaces(t, tt.ifaces...)
+
+			if got := getCNI(); got != tt.want {
+				t.Errorf("getCNI() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
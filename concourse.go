@@ -0,0 +1,47 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"os"
+	"strings"
+)
+
+// Concourse roles.
+const (
+	concourseWorker = "worker" // Concourse worker running Garden
+	concourseTask   = "task"   // Build step container created by Garden
+)
+
+// concourseDepotPaths are the Garden depot directories of a Concourse worker,
+// holding a bundle for each container it runs.
+var concourseDepotPaths = []string{
+	"/worker-state/depot",
+	"/opt/concourse/worker/depot",
+}
+
+// getConcourseRole returns whether the process is a Concourse worker or runs
+// in a task container the worker created, or "" if neither.
+func getConcourseRole(c *config) string {
+	// Check if the cgroup is one Garden created for a container.
+	if strings.Contains(readCgroup(c), "/garden/") {
+		return concourseTask
+	}
+
+	// Check if the worker's settings are present in the environment.
+	for _, v := range []string{"CONCOURSE_WORK_DIR", "CONCOURSE_TSA_HOST"} {
+		if _, ok := EnvironmentVariables[v]; ok {
+			return concourseWorker
+		}
+	}
+
+	// Check if a Garden depot exists.
+	for _, p := range concourseDepotPaths {
+		if _, err := os.Stat(p); err == nil {
+			return concourseWorker
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,41 @@
+package criprof
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGetConcourseRole(t *testing.T) {
+	tests := []struct {
+		name   string
+		cgroup string
+		env    map[string]string
+		depot  bool
+		want   string
+	}{
+		{"task", "0::/garden/0b2a0b8c-5f1d-4a6e-6c3f-2f1e7a9d4b11\n", nil, false, concourseTask},
+		{"worker env", "0::/system.slice/concourse-worker.service\n", map[string]string{"CONCOURSE_WORK_DIR": "/opt/concourse/worker"}, false, concourseWorker},
+		{"worker depot", "0::/\n", nil, true, concourseWorker},
+		{"neither", "0::/\n", nil, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withCgroupFiles(t, tt.cgroup)
+			withEnvironment(t, tt.env)
+
+			depot := t.TempDir()
+			if !tt.depot {
+				depot = filepath.Join(depot, "missing")
+			}
+
+			old := concourseDepotPaths
+			concourseDepotPaths = []string{depot}
+			t.Cleanup(func() { concourseDepotPaths = old })
+
+			if got := getConcourseRole(newConfig()); got != tt.want {
+				t.Errorf("getConcourseRole() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,58 +4,136 @@
 package criprof
 
 import (
+	"bufio"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"regexp"
+	"strings"
 )
 
 // IsContainer returns true if the application is running within a container
 // runtime/engine.
 func IsContainer() bool {
-	if _, err := os.Stat("/.dockerinit"); err == nil {
+	if _, err := os.Stat(dockerInitPath); err == nil {
 		return true
 	}
 
-	if _, err := os.Stat("/.dockerenv"); err == nil {
+	if _, err := os.Stat(dockerEnvPath); err == nil {
 		return true
 	}
 
-	if c := getContainerID(); c != "" {
+	if id := getContainerID(newConfig()); id != "undetermined" {
 		return true
 	}
 
 	return false
 }
 
-func getContainerID() string {
-	dockerIDMatch := regexp.MustCompile(`cpu\:\/docker\/([0-9a-z]+)`)
-	coreOSIDMatch := regexp.MustCompile(`cpuset\:\/system.slice\/docker-([0-9a-z]+)`)
+// Container ID layouts found in cgroup files.
+var (
+	dockerIDMatch      = regexp.MustCompile(`cpu\:\/docker\/([0-9a-z]+)`)
+	coreOSIDMatch      = regexp.MustCompile(`cpuset\:\/system.slice\/docker-([0-9a-z]+)`)
+	dockerScopeIDMatch = regexp.MustCompile(`^0::/.*docker-([0-9a-f]{64})\.scope`)
+	crioIDMatch        = regexp.MustCompile(`^0::/.*crio-([0-9a-f]{64})\.scope`)
+	containerdIDMatch  = regexp.MustCompile(`^0::/.*cri-containerd-([0-9a-f]{64})\.scope`)
+	shortIDMatch       = regexp.MustCompile(`^[0-9a-f]{12}$`)
+	fullIDMatch        = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	validIDMatch       = regexp.MustCompile(`^([0-9a-f]{64}|[0-9a-f]{12})$`)
+)
 
-	if _, err := os.Stat("/proc/self/cgroup"); os.IsExist(err) {
-		cgroup, _ := ioutil.ReadFile("/proc/self/cgroup")
-		strCgroup := string(cgroup)
-		loc := dockerIDMatch.FindStringIndex(strCgroup)
+// Sources of Inventory.ID, naming the layout it was read from.
+const (
+	idSourceDockerV1     = "docker-cgroup-v1"     // cpu:/docker/<id>
+	idSourceCoreOSV1     = "coreos-cgroup-v1"     // cpuset:/system.slice/docker-<id>.scope
+	idSourceDockerV2     = "docker-cgroup-v2"     // 0::/.../docker-<id>.scope
+	idSourceCRIOV2       = "crio-cgroup-v2"       // 0::/.../crio-<id>.scope
+	idSourceContainerdV2 = "containerd-cgroup-v2" // 0::/.../cri-containerd-<id>.scope
+	idSourceHostname     = "hostname-inferred"    // Short ID set as the hostname
+)
 
-		if loc != nil {
-			return strCgroup[loc[0]+12 : loc[1]-2]
-		}
+// containerIDPatterns are the cgroup layouts a container ID is read from, in
+// order of precedence.
+var containerIDPatterns = []struct {
+	match  *regexp.Regexp
+	source string
+}{
+	{dockerIDMatch, idSourceDockerV1},
+	{coreOSIDMatch, idSourceCoreOSV1},
+	{dockerScopeIDMatch, idSourceDockerV2},
+	{crioIDMatch, idSourceCRIOV2},
+	{containerdIDMatch, idSourceContainerdV2},
+}
+
+// getContainerID returns the ID of the running container from its cgroup
+// membership, or "undetermined" if it cannot be found.
+func getContainerID(c *config) string {
+	id, _ := parseContainerID(strings.NewReader(readCgroup(c)))
+	return id
+}
+
+// resolveContainerID returns the container ID and its source from the cgroup,
+// falling back to the hostname when it looks like a short container ID, as
+// Docker and Podman set it by default. The fallback is weaker evidence, so a
+// note describing the inference is returned alongside it.
+func resolveContainerID(c *config, hostname string) (string, string, string) {
+	id, source := parseContainerID(strings.NewReader(readCgroup(c)))
+	if id != "undetermined" {
+		return id, source, ""
+	}
+
+	if shortIDMatch.MatchString(hostname) {
+		return hostname, idSourceHostname, "container id inferred from hostname"
+	}
 
-		// cgroup not nil, not vanilla Docker. Check for CoreOS.
-		loc = coreOSIDMatch.FindStringIndex(strCgroup)
+	return id, "", ""
+}
+
+// parseContainerID extracts the container ID and the layout it was found in
+// from cgroup file contents read from r, trying each of containerIDPatterns in
+// turn. Only full 64 character and short 12 character hex IDs are accepted.
+// Accepting a reader lets the same logic run on captured cgroup data.
+func parseContainerID(r io.Reader) (string, string) {
+	var lines []string
 
-		if loc != nil {
-			return strCgroup[loc[0]+27:]
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	for _, p := range containerIDPatterns {
+		for _, line := range lines {
+			// Skip partial or malformed captures, which are not usable as keys.
+			if m := p.match.FindStringSubmatch(line); m != nil && validIDMatch.MatchString(m[1]) {
+				return m[1], p.source
+			}
 		}
 	}
 
-	return "undetermined"
+	return "undetermined", ""
+}
+
+// shortContainerID returns the 12 character short form of a container ID, as
+// shown by docker ps, or "" if the ID is undetermined.
+func shortContainerID(id string) string {
+	if !validIDMatch.MatchString(id) {
+		return ""
+	}
+
+	return id[:12]
 }
 
+// hostnameUnknown is reported as the hostname when it cannot be read.
+const hostnameUnknown = "unknown"
+
+// osHostname reads the kernel hostname. It is a variable so tests can
+// simulate failures.
+var osHostname = os.Hostname
+
 // getHostname returns the DNS hostname of the system.
 func getHostname() (string, error) {
 	// Use the os package to get the hostname of the system.
-	hostname, err := os.Hostname()
+	hostname, err := osHostname()
 	if err != nil {
 		return "", fmt.Errorf("failed to get hostname: %v", err)
 	}
@@ -0,0 +1,46 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import "os"
+
+// criCRIO is CRI-O, which only runs under Kubernetes.
+const criCRIO = "cri-o"
+
+// criSocket is a CRI endpoint and the runtime serving it.
+type criSocket struct {
+	path    string
+	runtime string
+}
+
+// criSockets maps the CRI endpoints of Kubernetes nodes to the runtime serving
+// them, checked in order. They are visible to pods that mount the socket
+// directory from the node, such as monitoring DaemonSets.
+var criSockets = []criSocket{
+	{"/run/containerd/containerd.sock", runtimeContainerD},
+	{"/var/run/containerd/containerd.sock", runtimeContainerD},
+	{"/host/run/containerd/containerd.sock", runtimeContainerD},
+	{"/var/run/crio/crio.sock", criCRIO},
+	{"/host/var/run/crio/crio.sock", criCRIO},
+	{"/var/run/cri-dockerd.sock", runtimeDocker},
+	{"/var/run/dockershim.sock", runtimeDocker},
+}
+
+// getCRIRuntime returns the container runtime serving the Kubernetes node's
+// CRI endpoint, or "" if not running under Kubernetes or no endpoint is
+// visible.
+func getCRIRuntime(scheduler string) string {
+	if !isKubernetesScheduler(scheduler) {
+		return ""
+	}
+
+	for _, s := range criSockets {
+		fi, err := os.Stat(s.path)
+		if err == nil && fi.Mode()&os.ModeSocket != 0 {
+			return s.runtime
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,62 @@
+package criprof
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+// withCRISockets points criSockets at the given endpoints beneath a temporary
+// directory, listening on those named in listen.
+func withCRISockets(t *testing.T, listen ...string) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	old := criSockets
+	criSockets = nil
+	for _, s := range old {
+		criSockets = append(criSockets, criSocket{filepath.Join(dir, filepath.Base(s.path)), s.runtime})
+	}
+	t.Cleanup(func() { criSockets = old })
+
+	for _, name := range listen {
+		l, err := net.Listen("unix", filepath.Join(dir, name))
+		if err != nil {
+			t.Skipf("cannot listen on a unix socket: %v", err)
+		}
+		t.Cleanup(func() { l.Close() })
+	}
+}
+
+func TestGetCRIRuntime(t *testing.T) {
+	tests := []struct {
+		name      string
+		socket    string
+		scheduler string
+		want      string
+	}{
+		{"containerd", "containerd.sock", schedulerKubernetes, runtimeContainerD},
+		{"cri-o", "crio.sock", schedulerKubernetes, criCRIO},
+		{"cri-dockerd", "cri-dockerd.sock", schedulerKubernetes, runtimeDocker},
+		{"not kubernetes", "containerd.sock", schedulerNomad, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withCRISockets(t, tt.socket)
+
+			if got := getCRIRuntime(tt.scheduler); got != tt.want {
+				t.Errorf("getCRIRuntime(%q) = %q, want %q", tt.scheduler, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCRIRuntimeNoSocket(t *testing.T) {
+	withCRISockets(t)
+
+	if got := getCRIRuntime(schedulerKubernetes); got != "" {
+		t.Errorf("getCRIRuntime() = %q without a socket, want empty", got)
+	}
+}
@@ -4,9 +4,17 @@
 package criprof
 
 import (
+	"bytes"
+	"context"
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // EnvironmentVariables is used to cache all environment variables read at
@@ -17,29 +25,319 @@ func init() {
 	EnvironmentVariables = environMap()
 }
 
+// ResetEnvironment re-reads the process environment into EnvironmentVariables.
+// It is useful for tests and for processes that change their environment after
+// start-up and want subsequent detections to observe it. It must not be called
+// concurrently with New.
+func ResetEnvironment() {
+	EnvironmentVariables = environMap()
+}
+
 // Inventory holds an application's container and runtime information.
 type Inventory struct {
-	Hostname    string `json:"hostname"`
-	ID          string `json:"id"`
-	ImageFormat string `json:"image_format"`
-	PID         int    `json:"pid"`
-	Runtime     string `json:"runtime"`
-	Scheduler   string `json:"scheduler"`
+	AWSExecutionEnv        string      `json:"aws_execution_env,omitempty"`
+	CgroupDepth            int         `json:"cgroup_depth,omitempty"`
+	CgroupFSWritable       bool        `json:"cgroupfs_writable,omitempty"`
+	CgroupWritable         bool        `json:"cgroup_writable,omitempty"`
+	ClockSource            string      `json:"clock_source,omitempty"`
+	CloudAccountID         string      `json:"cloud_account_id,omitempty"`
+	CloudInstanceID        string      `json:"cloud_instance_id,omitempty"`
+	CloudProject           string      `json:"cloud_project,omitempty"`
+	CloudProvider          string      `json:"cloud_provider,omitempty"`
+	CloudSubscriptionID    string      `json:"cloud_subscription_id,omitempty"`
+	ClusterDNS             string      `json:"cluster_dns,omitempty"`
+	ClusterName            string      `json:"cluster_name,omitempty"`
+	ClusterNameSource      string      `json:"cluster_name_source,omitempty"`
+	CNI                    string      `json:"cni,omitempty"`
+	ColdStart              bool        `json:"cold_start,omitempty"`
+	ConcourseRole          string      `json:"concourse_role,omitempty"`
+	ContainerEnv           string      `json:"container_env,omitempty"`
+	ContainerStartedAt     *time.Time  `json:"container_started_at,omitempty"`
+	CPURequest             int64       `json:"cpu_request,omitempty"`
+	CPUShares              int64       `json:"cpu_shares,omitempty"`
+	CPUWeight              int64       `json:"cpu_weight,omitempty"`
+	CRIRuntime             string      `json:"cri_runtime,omitempty"`
+	DetectionNotes         []string    `json:"detection_notes,omitempty"`
+	DevContainer           bool        `json:"dev_container,omitempty"`
+	DevContainerType       string      `json:"dev_container_type,omitempty"`
+	Distroless             bool        `json:"distroless,omitempty"`
+	DNSNdots               int         `json:"dns_ndots,omitempty"`
+	DockerFlavor           string      `json:"docker_flavor,omitempty"`
+	ECSContainerImage      string      `json:"ecs_container_image,omitempty"`
+	ECSContainerName       string      `json:"ecs_container_name,omitempty"`
+	ECSTaskARN             string      `json:"ecs_task_arn,omitempty"`
+	EffectivelyPrivileged  bool        `json:"effectively_privileged,omitempty"`
+	Emulated               bool        `json:"emulated,omitempty"`
+	Environment            string      `json:"environment"`
+	EphemeralContainer     bool        `json:"ephemeral_container,omitempty"`
+	GID                    int         `json:"gid"`
+	GPU                    bool        `json:"gpu,omitempty"`
+	GPUVendor              string      `json:"gpu_vendor,omitempty"`
+	GVisorPlatform         string      `json:"gvisor_platform,omitempty"`
+	HasEgress              bool        `json:"has_egress,omitempty"`
+	HostIPC                bool        `json:"host_ipc,omitempty"`
+	Hostname               string      `json:"hostname"`
+	HostNetwork            bool        `json:"host_network,omitempty"`
+	HostOS                 string      `json:"host_os,omitempty"`
+	HostPID                bool        `json:"host_pid,omitempty"`
+	HostUTS                bool        `json:"host_uts,omitempty"`
+	ID                     string      `json:"id"`
+	IDSource               string      `json:"id_source,omitempty"`
+	ImageFormat            string      `json:"image_format"`
+	InitCmdline            []string    `json:"init_cmdline,omitempty"`
+	Interfaces             []string    `json:"interfaces,omitempty"`
+	Isolation              string      `json:"isolation,omitempty"`
+	KataHypervisor         string      `json:"kata_hypervisor,omitempty"`
+	LambdaPackageType      string      `json:"lambda_package_type,omitempty"`
+	LogPath                string      `json:"log_path,omitempty"`
+	LXDInstanceType        string      `json:"lxd_instance_type,omitempty"`
+	MemoryRequest          int64       `json:"memory_request,omitempty"`
+	MMDSKeys               []string    `json:"mmds_keys,omitempty"`
+	Mounts                 []MountInfo `json:"mounts,omitempty"`
+	NestedContainer        bool        `json:"nested_container,omitempty"`
+	NestedVirt             bool        `json:"nested_virt,omitempty"`
+	NetworkMode            string      `json:"network_mode,omitempty"`
+	OCISpecVersion         string      `json:"oci_spec_version,omitempty"`
+	OpenFilesHardLimit     uint64      `json:"open_files_hard_limit,omitempty"`
+	OpenFilesLimit         uint64      `json:"open_files_limit,omitempty"`
+	OverallConfidence      float64     `json:"overall_confidence,omitempty"`
+	Partial                bool        `json:"partial,omitempty"`
+	PID                    int         `json:"pid"`
+	PidsLimit              int64       `json:"pids_limit,omitempty"`
+	PlatformVersion        string      `json:"platform_version,omitempty"`
+	PodmanMachine          bool        `json:"podman_machine,omitempty"`
+	PodName                string      `json:"pod_name,omitempty"`
+	PodSandbox             bool        `json:"pod_sandbox,omitempty"`
+	ProcMasked             bool        `json:"proc_masked,omitempty"`
+	PtraceRestricted       bool        `json:"ptrace_restricted,omitempty"`
+	Region                 string      `json:"region,omitempty"`
+	RestartPolicy          string      `json:"restart_policy,omitempty"`
+	RktStage1              string      `json:"rkt_stage1,omitempty"`
+	Rootless               bool        `json:"rootless,omitempty"`
+	RunAsRoot              bool        `json:"run_as_root"`
+	Runtime                string      `json:"runtime"`
+	RuntimeInitInjected    bool        `json:"runtime_init_injected,omitempty"`
+	Scheduler              string      `json:"scheduler"`
+	SchedulerFlavor        string      `json:"scheduler_flavor,omitempty"`
+	ScratchDir             string      `json:"scratch_dir,omitempty"`
+	ScratchFSType          string      `json:"scratch_fs_type,omitempty"`
+	SearchDomains          []string    `json:"search_domains,omitempty"`
+	SeccompProfile         string      `json:"seccomp_profile,omitempty"`
+	ServiceMesh            string      `json:"service_mesh,omitempty"`
+	ShmSizeBytes           int64       `json:"shm_size_bytes,omitempty"`
+	ShortID                string      `json:"short_id,omitempty"`
+	SystemdInContainer     bool        `json:"systemd_in_container,omitempty"`
+	TerminationGracePeriod int64       `json:"termination_grace_period_seconds,omitempty"`
+	TokenAudience          []string    `json:"token_audience,omitempty"`
+	UID                    int         `json:"uid"`
+	UIDRemapped            bool        `json:"uid_remapped,omitempty"`
+	UptimeSeconds          int64       `json:"uptime_seconds,omitempty"`
+	WasmEngine             string      `json:"wasm_engine,omitempty"`
+	WorkloadIdentity       string      `json:"workload_identity,omitempty"`
+	WSL                    bool        `json:"wsl,omitempty"`
+	WSLVersion             int         `json:"wsl_version,omitempty"`
+
+	reasons map[string]UndeterminedReason
 }
 
-// New returns a new Inventory with populated values.
+// New returns a new Inventory with populated values using the default
+// detection settings. It reads files under /proc, /sys, /etc and /run, and,
+// unless WithoutNetwork is given, probes the Kubernetes API and the Docker
+// Swarm port for scheduler detection and queries the Docker and LXD sockets
+// when they are mounted, each bounded by the probe timeout. Cloud metadata
+// services and filesystem walks are left to Profile.
 func New() *Inventory {
-	f, _ := getImageFormat()
-	h, _ := getHostname()
+	return NewWithOptions()
+}
 
-	return &Inventory{
-		Hostname:    h,
-		ID:          getContainerID(),
-		ImageFormat: f,
-		PID:         os.Getpid(),
-		Runtime:     getRuntime(),
-		Scheduler:   getScheduler(),
+// Runtime returns the detected container runtime, such as "docker", without
+// building a full Inventory.
+func Runtime() string {
+	return getRuntime(newConfig())
+}
+
+// Scheduler returns the detected scheduler, such as "kubernetes", without
+// building a full Inventory. It uses the default detection settings.
+func Scheduler() string {
+	return getScheduler(newConfig())
+}
+
+// ImageFormat returns the detected container image format, or "undetermined"
+// if it cannot be determined.
+func ImageFormat() string {
+	f, err := getImageFormat(newConfig())
+	if err != nil {
+		return formatUndetermined
 	}
+
+	return f
+}
+
+// NewWithOptions returns a new Inventory with populated values, using opts to
+// tune detection.
+func NewWithOptions(opts ...Option) *Inventory {
+	return newInventory(newConfig(opts...))
+}
+
+// NewWithContext returns a new Inventory like NewWithOptions, bounding network
+// probes by ctx. If ctx is done before detection finishes, NewWithContext
+// returns ctx's error, or with WithPartialResults, the Inventory gathered so
+// far with Partial set and a detection note recording the truncation.
+func NewWithContext(ctx context.Context, opts ...Option) (*Inventory, error) {
+	c := newConfig(append(opts, withContext(ctx))...)
+	inv := newInventory(c)
+
+	if err := ctx.Err(); err != nil {
+		if !c.partial {
+			return nil, err
+		}
+
+		inv.Partial = true
+		inv.DetectionNotes = append(inv.DetectionNotes, "detection truncated: "+err.Error())
+	}
+
+	return inv, nil
+}
+
+// newInventory returns a new Inventory with populated values, detected with
+// the settings in c.
+func newInventory(c *config) *Inventory {
+	resetDMICache()
+	resetMountInfoCache()
+	f, ferr := getImageFormat(c)
+	h, err := getHostname()
+	if err != nil {
+		h = hostnameUnknown
+	}
+	id, idSource, idNote := resolveContainerID(c, h)
+	notes := getDetectionNotes(c)
+	if idNote != "" {
+		notes = append(notes, idNote)
+	}
+	dc := getDevContainerType()
+	cpuShares, cpuWeight := getCPUShares()
+	nofile, nofileHard := getOpenFilesLimit()
+	depth := getCgroupDepth(c)
+	gpu := getGPUVendor()
+	r := getRuntime(c)
+	lxd := getLXDInstanceType(c, r)
+	rkt := getRktStage1(r)
+	sch := getScheduler(c)
+	cluster, clusterSource := getClusterNameFromEnv()
+	started := getContainerStartedAt()
+	dns, search := getClusterDNS(sch)
+	env := getEnvironment(r, sch)
+	netMode := getNetworkMode(env)
+	uid := os.Getuid()
+	remapped := getUIDRemapped(uid)
+	wsl := getWSLVersion()
+
+	inv := &Inventory{
+		AWSExecutionEnv:        getAWSExecutionEnv(),
+		CgroupDepth:            depth,
+		CgroupFSWritable:       isCgroupFSWritable(env),
+		CgroupWritable:         isCgroupWritable(),
+		ClockSource:            getClockSource(),
+		CloudProvider:          getCloudProvider(),
+		ClusterDNS:             dns,
+		ClusterName:            cluster,
+		ClusterNameSource:      clusterSource,
+		CNI:                    getCNI(),
+		ColdStart:              isColdStart(env, started),
+		ConcourseRole:          getConcourseRole(c),
+		ContainerEnv:           getContainerEnv(),
+		ContainerStartedAt:     started,
+		CPURequest:             getResourceRequest(cpuRequestFiles),
+		CPUShares:              cpuShares,
+		CPUWeight:              cpuWeight,
+		CRIRuntime:             getCRIRuntime(sch),
+		DetectionNotes:         notes,
+		DevContainer:           dc != "",
+		DevContainerType:       dc,
+		Distroless:             isDistroless("/"),
+		DNSNdots:               getDNSNdots(),
+		DockerFlavor:           getDockerFlavor(r, h),
+		EffectivelyPrivileged:  isEffectivelyPrivileged(),
+		Emulated:               isEmulated(),
+		Environment:            env,
+		EphemeralContainer:     isEphemeralContainer(),
+		GID:                    os.Getgid(),
+		GPU:                    gpu != "",
+		GPUVendor:              gpu,
+		GVisorPlatform:         getGVisorPlatform(r),
+		HasEgress:              hasEgress(c),
+		HostIPC:                isHostNamespace("ipc"),
+		Hostname:               h,
+		HostNetwork:            isHostNetwork(netMode),
+		HostOS:                 getHostOS(),
+		HostPID:                isHostPID(),
+		HostUTS:                isHostNamespace("uts"),
+		ID:                     id,
+		IDSource:               idSource,
+		ImageFormat:            f,
+		InitCmdline:            getInitCmdline(false),
+		Interfaces:             getInterfaces(),
+		Isolation:              getIsolation(r, rkt, lxd),
+		KataHypervisor:         getKataHypervisor(r),
+		LambdaPackageType:      getLambdaPackageType(),
+		LXDInstanceType:        lxd,
+		MemoryRequest:          getResourceRequest(memoryRequestFiles),
+		MMDSKeys:               getMMDSKeys(c, r),
+		NestedContainer:        depth > 1,
+		NestedVirt:             getNestedVirt(),
+		NetworkMode:            netMode,
+		OCISpecVersion:         getOCISpecVersion(),
+		OpenFilesHardLimit:     nofileHard,
+		OpenFilesLimit:         nofile,
+		OverallConfidence:      overallConfidence(r, sch, f),
+		PID:                    os.Getpid(),
+		PidsLimit:              getPidsLimit(),
+		PodmanMachine:          isPodmanMachine(h),
+		PodName:                getPodmanPod(),
+		PodSandbox:             isPodSandbox(),
+		ProcMasked:             isProcMasked(),
+		PtraceRestricted:       isPtraceRestricted(),
+		RestartPolicy:          getRestartPolicy(),
+		RktStage1:              rkt,
+		Rootless:               getRootless(r),
+		RunAsRoot:              isRunAsRoot(uid, remapped),
+		Runtime:                r,
+		RuntimeInitInjected:    isRuntimeInitInjected(),
+		Scheduler:              sch,
+		SchedulerFlavor:        getSchedulerFlavor(sch, nil),
+		SearchDomains:          search,
+		SeccompProfile:         getSeccompProfile(),
+		ServiceMesh:            getServiceMesh(),
+		ShmSizeBytes:           getShmSize(),
+		ShortID:                shortContainerID(id),
+		SystemdInContainer:     isSystemdInContainer(r),
+		TerminationGracePeriod: getTerminationGracePeriod(),
+		TokenAudience:          getTokenAudience(sch),
+		UID:                    uid,
+		UIDRemapped:            remapped,
+		UptimeSeconds:          containerUptime(started),
+		WasmEngine:             getWasmEngine(),
+		WorkloadIdentity:       getWorkloadIdentityFromEnv(sch),
+		WSL:                    wsl != 0,
+		WSLVersion:             wsl,
+	}
+
+	if c.profile {
+		enrichProfile(inv, c)
+	}
+
+	// Probes may run while the literal above is evaluated, so only now is it
+	// known whether a deadline cut any short.
+	inv.Partial = c.truncated
+
+	inv.reasons = map[string]UndeterminedReason{
+		"id":           undeterminedReason(inv.ID, nil, false),
+		"image_format": undeterminedReason(f, ferr, false),
+		"runtime":      undeterminedReason(r, nil, false),
+		"scheduler":    undeterminedReason(sch, nil, !c.network || c.truncated),
+	}
+
+	return inv
 }
 
 // JSON returns the Inventory as JSON string.
@@ -52,3 +350,168 @@ func (i Inventory) JSON() string {
 
 	return string(j)
 }
+
+// Map returns every Inventory field as a flat map of strings keyed by its JSON
+// field name, suitable for use as metric labels or trace attributes. Unlike
+// JSON(), zero values are included: unset strings, lists and times are "",
+// numbers and booleans are formatted as such, and lists are encoded as compact
+// JSON.
+func (i Inventory) Map() map[string]string {
+	v := reflect.ValueOf(i)
+	t := v.Type()
+
+	m := make(map[string]string, t.NumField())
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		m[name] = formatField(v.Field(n))
+	}
+
+	return m
+}
+
+// formatField returns the string form of an Inventory field value for Map.
+func formatField(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		if v.IsNil() {
+			return ""
+		}
+	}
+
+	if tm, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		return formatField(v.Elem())
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	}
+
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return ""
+	}
+
+	return string(b)
+}
+
+// jsonFields returns the fields present in JSON() as a flat map of strings,
+// with list or object fields encoded as compact JSON.
+func (i Inventory) jsonFields() map[string]string {
+	j, err := json.Marshal(i)
+	if err != nil {
+		return nil
+	}
+
+	var fields map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(j))
+	d.UseNumber()
+	if err := d.Decode(&fields); err != nil {
+		return nil
+	}
+
+	m := make(map[string]string, len(fields))
+	for k, v := range fields {
+		switch v := v.(type) {
+		case string:
+			m[k] = v
+		case json.Number:
+			m[k] = v.String()
+		case bool:
+			m[k] = strconv.FormatBool(v)
+		default:
+			b, _ := json.Marshal(v)
+			m[k] = string(b)
+		}
+	}
+
+	return m
+}
+
+// Logfmt returns the Inventory as a single logfmt line of key=value pairs
+// sorted by key, holding the fields present in JSON() formatted as in Map().
+// Values that are empty or contain spaces, quotes, equals signs or control
+// characters are quoted.
+func (i Inventory) Logfmt() string {
+	m := i.jsonFields()
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for n, k := range keys {
+		if n > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(m[k]))
+	}
+
+	return b.String()
+}
+
+// logfmtValue quotes v if it cannot be written bare in a logfmt pair.
+func logfmtValue(v string) string {
+	if v == "" || strings.IndexFunc(v, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == 0x7f
+	}) >= 0 {
+		return strconv.Quote(v)
+	}
+
+	return v
+}
+
+// Attribute is a key/value pair shaped like an OpenTelemetry attribute, so the
+// Inventory can enrich log records without this package depending on
+// OpenTelemetry.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Attributes returns the determined Inventory fields as attributes sorted by
+// key. Keys are the JSON field names prefixed with "criprof.", and fields that
+// are omitted from JSON(), empty or undetermined are omitted.
+func (i Inventory) Attributes() []Attribute {
+	m := i.jsonFields()
+
+	attrs := make([]Attribute, 0, len(m))
+	for k, v := range m {
+		if v == "" || v == "undetermined" {
+			continue
+		}
+
+		attrs = append(attrs, Attribute{Key: "criprof." + k, Value: v})
+	}
+
+	sort.Slice(attrs, func(a, b int) bool { return attrs[a].Key < attrs[b].Key })
+
+	return attrs
+}
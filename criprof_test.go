@@ -0,0 +1,193 @@
+package criprof
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResetEnvironment(t *testing.T) {
+	withEnvironment(t, map[string]string{})
+	t.Setenv("CRIPROF_TEST_RESET", "1")
+
+	ResetEnvironment()
+
+	if got := EnvironmentVariables["CRIPROF_TEST_RESET"]; got != "1" {
+		t.Errorf("EnvironmentVariables[CRIPROF_TEST_RESET] = %q, want %q", got, "1")
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	b.ReportAllocs()
+
+	// Run detection b.N times, without network probes whose timing depends on
+	// the environment.
+	for i := 0; i < b.N; i++ {
+		NewWithOptions(WithoutNetwork())
+	}
+}
+
+func TestInventoryMap(t *testing.T) {
+	i := Inventory{
+		DevContainer:     true,
+		DevContainerType: devContainerCodespaces,
+		Environment:      environmentContainer,
+		Hostname:         "web-1",
+		ID:               "4f3a9c2b1d0e",
+		ImageFormat:      formatDocker,
+		InitCmdline:      []string{"/app", "--serve"},
+		PID:              1234,
+		Runtime:          runtimeDocker,
+		Scheduler:        schedulerKubernetes,
+		UID:              1000,
+		GID:              1000,
+	}
+
+	want := map[string]string{
+		"dev_container":      "true",
+		"dev_container_type": "codespaces",
+		"environment":        "container",
+		"hostname":           "web-1",
+		"id":                 "4f3a9c2b1d0e",
+		"image_format":       "docker",
+		"init_cmdline":       `["/app","--serve"]`,
+		"pid":                "1234",
+		"runtime":            "docker",
+		"scheduler":          "kubernetes",
+		"uid":                "1000",
+		"gid":                "1000",
+		"run_as_root":        "false",
+	}
+
+	got := i.Map()
+
+	var fields int
+	typ := reflect.TypeOf(i)
+	for n := 0; n < typ.NumField(); n++ {
+		if typ.Field(n).PkgPath == "" {
+			fields++
+		}
+	}
+
+	if len(got) != fields {
+		t.Errorf("Map() returned %d fields, want every one of the %d exported fields", len(got), fields)
+	}
+
+	want["cgroup_depth"] = "0"
+	want["container_started_at"] = ""
+	want["gpu"] = "false"
+	want["mounts"] = ""
+
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Map()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestInventoryAttributes(t *testing.T) {
+	i := Inventory{
+		Environment: environmentContainer,
+		Hostname:    "web-1",
+		ID:          "undetermined",
+		ImageFormat: formatUndetermined,
+		PID:         1234,
+		Runtime:     runtimeDocker,
+		Scheduler:   schedulerUndetermined,
+		UID:         1000,
+		GID:         1000,
+	}
+
+	want := []Attribute{
+		{"criprof.environment", "container"},
+		{"criprof.gid", "1000"},
+		{"criprof.hostname", "web-1"},
+		{"criprof.pid", "1234"},
+		{"criprof.run_as_root", "false"},
+		{"criprof.runtime", "docker"},
+		{"criprof.uid", "1000"},
+	}
+
+	got := i.Attributes()
+	if len(got) != len(want) {
+		t.Fatalf("Attributes() = %v, want %v", got, want)
+	}
+
+	for n := range want {
+		if got[n] != want[n] {
+			t.Errorf("Attributes()[%d] = %v, want %v", n, got[n], want[n])
+		}
+	}
+}
+
+func TestDetectionHelpers(t *testing.T) {
+	withOverrides(t)
+	withEnvironment(t, map[string]string{
+		overrideRuntime:     "podman",
+		overrideScheduler:   "nomad",
+		overrideImageFormat: "oci",
+	})
+
+	if got := Runtime(); got != "podman" {
+		t.Errorf("Runtime() = %q, want %q", got, "podman")
+	}
+
+	if got := Scheduler(); got != "nomad" {
+		t.Errorf("Scheduler() = %q, want %q", got, "nomad")
+	}
+
+	if got := ImageFormat(); got != "oci" {
+		t.Errorf("ImageFormat() = %q, want %q", got, "oci")
+	}
+}
+
+func TestHostnameUnknown(t *testing.T) {
+	old := osHostname
+	osHostname = func() (string, error) { return "", errors.New("uname failed") }
+	t.Cleanup(func() { osHostname = old })
+
+	if got := NewWithOptions(WithoutNetwork()).Hostname; got != hostnameUnknown {
+		t.Errorf("Hostname = %q, want %q", got, hostnameUnknown)
+	}
+}
+
+func TestInventoryLogfmt(t *testing.T) {
+	i := Inventory{
+		Environment: environmentContainer,
+		Hostname:    "web-1",
+		ID:          "4f3a9c2b1d0e",
+		ImageFormat: formatDocker,
+		InitCmdline: []string{"/app", "--serve"},
+		PID:         1234,
+		Runtime:     runtimeDocker,
+		Scheduler:   schedulerKubernetes,
+	}
+
+	want := `environment=container gid=0 hostname=web-1 id=4f3a9c2b1d0e image_format=docker ` +
+		`init_cmdline="[\"/app\",\"--serve\"]" pid=1234 run_as_root=false runtime=docker scheduler=kubernetes uid=0`
+	if got := i.Logfmt(); got != want {
+		t.Errorf("Logfmt() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLogfmtValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"docker", "docker"},
+		{"", `""`},
+		{"two words", `"two words"`},
+		{"a=b", `"a=b"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"tab\there", `"tab\there"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"ünïcode", "ünïcode"},
+	}
+
+	for _, tt := range tests {
+		if got := logfmtValue(tt.in); got != tt.want {
+			t.Errorf("logfmtValue(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
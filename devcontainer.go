@@ -0,0 +1,37 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+// Detectable development container environments.
+const (
+	devContainerCodespaces = "codespaces" // GitHub Codespaces
+	devContainerGitpod     = "gitpod"     // Gitpod
+	devContainerVSCode     = "vscode"     // VS Code Dev Containers
+)
+
+// getDevContainerType returns the development container environment the
+// application is running in, or "" if none is detected.
+func getDevContainerType() string {
+	// Check if CODESPACES environment variable is set by GitHub Codespaces.
+	if _, ok := EnvironmentVariables["CODESPACES"]; ok {
+		return devContainerCodespaces
+	}
+
+	// Check if GITPOD_WORKSPACE_ID environment variable is set by Gitpod.
+	if _, ok := EnvironmentVariables["GITPOD_WORKSPACE_ID"]; ok {
+		return devContainerGitpod
+	}
+
+	// Check if REMOTE_CONTAINERS environment variable is set by VS Code.
+	if _, ok := EnvironmentVariables["REMOTE_CONTAINERS"]; ok {
+		return devContainerVSCode
+	}
+
+	// Check if DEVCONTAINER environment variable is set by the devcontainer CLI.
+	if _, ok := EnvironmentVariables["DEVCONTAINER"]; ok {
+		return devContainerVSCode
+	}
+
+	return ""
+}
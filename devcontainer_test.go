@@ -0,0 +1,27 @@
+package criprof
+
+import "testing"
+
+func TestGetDevContainerType(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"codespaces", map[string]string{"CODESPACES": "true"}, devContainerCodespaces},
+		{"gitpod", map[string]string{"GITPOD_WORKSPACE_ID": "abc"}, devContainerGitpod},
+		{"vscode", map[string]string{"REMOTE_CONTAINERS": "true"}, devContainerVSCode},
+		{"devcontainer", map[string]string{"DEVCONTAINER": "true"}, devContainerVSCode},
+		{"none", map[string]string{"HOME": "/root"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEnvironment(t, tt.env)
+
+			if got := getDevContainerType(); got != tt.want {
+				t.Errorf("getDevContainerType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// dmiPath is the directory exposing the system's DMI/SMBIOS identification
+// fields.
+var dmiPath = "/sys/class/dmi/id"
+
+// dmiCache memoizes DMI reads, which several detectors share, for the duration
+// of a detection pass. Entries are keyed by file path.
+var dmiCache = struct {
+	sync.Mutex
+	values map[string]dmiValue
+}{values: make(map[string]dmiValue)}
+
+// dmiValue is a cached readDMI result.
+type dmiValue struct {
+	v   string
+	err error
+}
+
+// resetDMICache discards memoized DMI reads so the next pass observes the
+// current values.
+func resetDMICache() {
+	dmiCache.Lock()
+	dmiCache.values = make(map[string]dmiValue)
+	dmiCache.Unlock()
+}
+
+// readDMI returns the DMI field, such as "sys_vendor" or "product_name", with
+// surrounding whitespace and the trailing newline removed. Each field is read
+// at most once per detection pass.
+func readDMI(field string) (string, error) {
+	p := filepath.Join(dmiPath, field)
+
+	dmiCache.Lock()
+	defer dmiCache.Unlock()
+
+	if c, ok := dmiCache.values[p]; ok {
+		return c.v, c.err
+	}
+
+	v, err := ioutil.ReadFile(p)
+	if err != nil {
+		dmiCache.values[p] = dmiValue{err: err}
+		return "", err
+	}
+
+	c := dmiValue{v: strings.TrimSpace(string(v))}
+	dmiCache.values[p] = c
+
+	return c.v, nil
+}
@@ -0,0 +1,57 @@
+package criprof
+
+import "testing"
+
+// withDMI points dmiPath at a temporary directory holding the given DMI
+// fields for the duration of the test.
+func withDMI(t *testing.T, fields map[string]string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, value := range fields {
+		writeTestFile(t, dir, name, value)
+	}
+
+	old := dmiPath
+	dmiPath = dir
+	t.Cleanup(func() { dmiPath = old })
+}
+
+func TestReadDMI(t *testing.T) {
+	withDMI(t, map[string]string{
+		"sys_vendor":   "QEMU\n",
+		"product_name": "  Standard PC (i440FX + PIIX, 1996)  \n",
+	})
+
+	if got, err := readDMI("sys_vendor"); err != nil || got != "QEMU" {
+		t.Errorf("readDMI(sys_vendor) = %q, %v, want %q", got, err, "QEMU")
+	}
+
+	if got, _ := readDMI("product_name"); got != "Standard PC (i440FX + PIIX, 1996)" {
+		t.Errorf("readDMI(product_name) = %q, want trimmed value", got)
+	}
+
+	if _, err := readDMI("board_vendor"); err == nil {
+		t.Error("readDMI(board_vendor) returned no error for a missing field")
+	}
+}
+
+func TestReadDMICached(t *testing.T) {
+	withDMI(t, map[string]string{"product_name": "Firecracker\n"})
+
+	if got, _ := readDMI("product_name"); got != "Firecracker" {
+		t.Fatalf("readDMI(product_name) = %q, want %q", got, "Firecracker")
+	}
+
+	writeTestFile(t, dmiPath, "product_name", "KVM\n")
+
+	if got, _ := readDMI("product_name"); got != "Firecracker" {
+		t.Errorf("readDMI(product_name) = %q after the file changed, want the cached %q", got, "Firecracker")
+	}
+
+	resetDMICache()
+
+	if got, _ := readDMI("product_name"); got != "KVM" {
+		t.Errorf("readDMI(product_name) = %q after resetDMICache, want %q", got, "KVM")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// resolvConfPath is the resolver configuration the runtime injects into the
+// container.
+var resolvConfPath = "/etc/resolv.conf"
+
+// resolvConf is the subset of resolv.conf(5) used for detection.
+type resolvConf struct {
+	nameservers []string
+	search      []string
+	ndots       int
+}
+
+// Resolver ndots bounds from resolv.conf(5).
+const (
+	defaultNdots = 1
+	maxNdots     = 15
+)
+
+// readResolvConf returns the parsed resolvConfPath, or nil if it cannot be
+// read.
+func readResolvConf() *resolvConf {
+	f, err := os.Open(resolvConfPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	return parseResolvConf(f)
+}
+
+// parseResolvConf parses the nameserver, search and options lines of a
+// resolv.conf. As in the resolver, the last search line and the last ndots
+// option win.
+func parseResolvConf(r io.Reader) *resolvConf {
+	rc := &resolvConf{ndots: defaultNdots}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+			continue
+		}
+
+		switch fields[0] {
+		case "nameserver":
+			rc.nameservers = append(rc.nameservers, fields[1])
+		case "search":
+			rc.search = fields[1:]
+		case "options":
+			for _, opt := range fields[1:] {
+				if !strings.HasPrefix(opt, "ndots:") {
+					continue
+				}
+
+				n, err := strconv.Atoi(strings.TrimPrefix(opt, "ndots:"))
+				if err != nil || n < 0 {
+					continue
+				}
+				if n > maxNdots {
+					n = maxNdots
+				}
+				rc.ndots = n
+			}
+		}
+	}
+
+	return rc
+}
+
+// getClusterDNS returns the cluster DNS server and search domains Kubernetes
+// configured for the pod, or empty values if not running under Kubernetes.
+func getClusterDNS(scheduler string) (string, []string) {
+	if !isKubernetesScheduler(scheduler) {
+		return "", nil
+	}
+
+	rc := readResolvConf()
+	if rc == nil || len(rc.nameservers) == 0 {
+		return "", nil
+	}
+
+	return rc.nameservers[0], rc.search
+}
+
+// getDNSNdots returns the resolver's ndots option: names with fewer dots are
+// tried against each search domain before being resolved as given. Kubernetes
+// sets 5, so external names cost extra lookups. It returns 0 if resolv.conf
+// cannot be read.
+func getDNSNdots() int {
+	rc := readResolvConf()
+	if rc == nil {
+		return 0
+	}
+
+	return rc.ndots
+}
@@ -0,0 +1,68 @@
+package criprof
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testKubernetesResolvConf = `# Generated by kubelet
+search shop.svc.cluster.local svc.cluster.local cluster.local ec2.internal
+nameserver 10.96.0.10
+options ndots:5
+`
+
+func TestParseResolvConf(t *testing.T) {
+	rc := parseResolvConf(strings.NewReader(testKubernetesResolvConf))
+
+	if !reflect.DeepEqual(rc.nameservers, []string{"10.96.0.10"}) {
+		t.Errorf("nameservers = %v, want [10.96.0.10]", rc.nameservers)
+	}
+
+	wantSearch := []string{"shop.svc.cluster.local", "svc.cluster.local", "cluster.local", "ec2.internal"}
+	if !reflect.DeepEqual(rc.search, wantSearch) {
+		t.Errorf("search = %v, want %v", rc.search, wantSearch)
+	}
+}
+
+func TestGetClusterDNS(t *testing.T) {
+	old := resolvConfPath
+	resolvConfPath = writeTestFile(t, t.TempDir(), "resolv.conf", testKubernetesResolvConf)
+	t.Cleanup(func() { resolvConfPath = old })
+
+	dns, search := getClusterDNS(schedulerKubernetes)
+	if dns != "10.96.0.10" || len(search) != 4 || search[0] != "shop.svc.cluster.local" {
+		t.Errorf("getClusterDNS() = %q, %v, want the kubelet's DNS configuration", dns, search)
+	}
+
+	if dns, search := getClusterDNS(schedulerNomad); dns != "" || search != nil {
+		t.Errorf("getClusterDNS(nomad) = %q, %v, want empty", dns, search)
+	}
+}
+
+func TestGetDNSNdots(t *testing.T) {
+	tests := []struct {
+		name       string
+		resolvConf string
+		want       int
+	}{
+		{"kubernetes", testKubernetesResolvConf, 5},
+		{"default", "nameserver 8.8.8.8\n", defaultNdots},
+		{"with other options", "nameserver 8.8.8.8\noptions timeout:2 ndots:2 attempts:3\n", 2},
+		{"last wins", "options ndots:5\noptions ndots:1\n", 1},
+		{"capped", "options ndots:30\n", maxNdots},
+		{"malformed", "options ndots:many\n", defaultNdots},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := resolvConfPath
+			resolvConfPath = writeTestFile(t, t.TempDir(), "resolv.conf", tt.resolvConf)
+			t.Cleanup(func() { resolvConfPath = old })
+
+			if got := getDNSNdots(); got != tt.want {
+				t.Errorf("getDNSNdots() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Docker flavors.
+const (
+	dockerDesktop = "desktop" // Docker Desktop, running the engine in a LinuxKit VM
+	dockerEngine  = "engine"  // Docker Engine running natively on the host
+)
+
+// desktopMarkerPath is the directory Docker Desktop's VM exposes to
+// containers for host file sharing.
+var desktopMarkerPath = "/run/desktop"
+
+// getDockerFlavor returns whether a Docker runtime is Docker Desktop or a
+// native Docker Engine, or "" if the runtime is not Docker.
+func getDockerFlavor(runtime, hostname string) string {
+	if runtime != runtimeDocker {
+		return ""
+	}
+
+	if isDockerDesktop(hostname) {
+		return dockerDesktop
+	}
+
+	return dockerEngine
+}
+
+// isDockerDesktop returns true if running inside Docker Desktop's VM.
+func isDockerDesktop(hostname string) bool {
+	// Check if the kernel is Docker Desktop's LinuxKit build.
+	if strings.Contains(readKernelRelease(), "linuxkit") {
+		return true
+	}
+
+	// Check if Docker Desktop's host file sharing directory exists.
+	if _, err := os.Stat(desktopMarkerPath); err == nil {
+		return true
+	}
+
+	// Check if the hostname is that of the Docker Desktop VM, as seen by
+	// containers sharing its network namespace.
+	return hostname == "docker-desktop"
+}
+
+// newUnixClient returns an HTTP client that sends every request to the unix
+// socket at path, bounded by the configured timeout. Keep-alives are disabled
+// so no idle connection outlives the probe.
+func newUnixClient(c *config, path string) *http.Client {
+	return &http.Client{
+		Timeout: c.probeTimeout(),
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		},
+	}
+}
+
+// dockerSocketPath is the Docker Engine API socket, present in containers that
+// mount it to manage Docker.
+var dockerSocketPath = "/var/run/docker.sock"
+
+// dockerInfo is the subset of the Docker Engine /info response used for
+// detection.
+type dockerInfo struct {
+	Swarm struct {
+		LocalNodeState string `json:"LocalNodeState"`
+	} `json:"Swarm"`
+}
+
+// getDockerInfo queries the Docker Engine /info endpoint over
+// dockerSocketPath, returning nil if the socket is absent or unusable.
+func getDockerInfo(c *config) *dockerInfo {
+	if _, err := os.Stat(dockerSocketPath); err != nil {
+		return nil
+	}
+
+	client := newUnixClient(c, dockerSocketPath)
+
+	var info *dockerInfo
+	c.probe(func() bool {
+		resp, err := c.get(client, "http://docker/info")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return false
+		}
+
+		var di dockerInfo
+		if err := json.NewDecoder(resp.Body).Decode(&di); err != nil {
+			return false
+		}
+
+		info = &di
+		return true
+	})
+
+	return info
+}
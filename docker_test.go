@@ -0,0 +1,98 @@
+package criprof
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetDockerFlavor(t *testing.T) {
+	tests := []struct {
+		name      string
+		osrelease string
+		desktop   bool
+		hostname  string
+		want      string
+	}{
+		{"linuxkit kernel", "5.15.49-linuxkit\n", false, "4f3a9c2b1d0e", dockerDesktop},
+		{"desktop marker", "6.1.0-18-amd64\n", true, "4f3a9c2b1d0e", dockerDesktop},
+		{"desktop hostname", "6.1.0-18-amd64\n", false, "docker-desktop", dockerDesktop},
+		{"engine", "6.1.0-18-amd64\n", false, "4f3a9c2b1d0e", dockerEngine},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t, testProcess{"1", "0", "app"})
+			writeTestFile(t, dir, "sys/kernel/osrelease", tt.osrelease)
+
+			marker := t.TempDir()
+			if !tt.desktop {
+				marker = filepath.Join(marker, "missing")
+			}
+
+			old := desktopMarkerPath
+			desktopMarkerPath = marker
+			t.Cleanup(func() { desktopMarkerPath = old })
+
+			if got := getDockerFlavor(runtimeDocker, tt.hostname); got != tt.want {
+				t.Errorf("getDockerFlavor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := getDockerFlavor(runtimePodman, "docker-desktop"); got != "" {
+		t.Errorf("getDockerFlavor(podman) = %q, want empty", got)
+	}
+}
+
+// withDockerSocket serves the Docker Engine /info response body on a fake
+// Docker socket for the duration of the test.
+func withDockerSocket(t *testing.T, info string) {
+	t.Helper()
+
+	p := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", p)
+	if err != nil {
+		t.Skipf("cannot listen on a unix socket: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/info" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(info))
+	}))
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	old := dockerSocketPath
+	dockerSocketPath = p
+	t.Cleanup(func() { dockerSocketPath = old })
+}
+
+func TestIsSwarmDockerSocket(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		want  bool
+	}{
+		{"active", "active", true},
+		{"inactive", "inactive", false},
+		{"pending", "pending", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withDockerSocket(t, `{"ServerVersion":"24.0.7","Swarm":{"NodeID":"x7kq","LocalNodeState":"`+tt.state+`"}}`)
+
+			if got := isSwarm(newConfig(WithTimeout(time.Second))); got != tt.want {
+				t.Errorf("isSwarm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ecsIntrospectionURL is the ECS container agent introspection endpoint for
+// task lookups, reachable from tasks on EC2 container instances.
+var ecsIntrospectionURL = "http://127.0.0.1:51678/v1/tasks"
+
+// ecsTask is the subset of an ECS agent introspection task used for
+// inventory.
+type ecsTask struct {
+	Arn        string `json:"Arn"`
+	Family     string `json:"Family"`
+	Version    string `json:"Version"`
+	Containers []struct {
+		DockerID   string `json:"DockerId"`
+		DockerName string `json:"DockerName"`
+		Image      string `json:"Image"`
+		Name       string `json:"Name"`
+	} `json:"Containers"`
+}
+
+// getECSTask returns the ECS task running the container id, as reported by
+// the agent introspection endpoint, or nil if unavailable. The agent is only
+// queried for ECS tasks on EC2, since Fargate does not expose it, and only
+// with a full 64-character container ID, which is what it is keyed by.
+func getECSTask(c *config, id string) *ecsTask {
+	if !c.network || !fullIDMatch.MatchString(id) || getAWSExecutionEnv() != awsECSEC2 {
+		return nil
+	}
+
+	u := ecsIntrospectionURL + "?" + url.Values{"dockerid": {id}}.Encode()
+
+	var task *ecsTask
+
+	client := &http.Client{Timeout: c.probeTimeout()}
+	c.probe(func() bool {
+		resp, err := c.get(client, u)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return false
+		}
+
+		var t ecsTask
+		if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+			return false
+		}
+
+		task = &t
+		return true
+	})
+
+	return task
+}
+
+// containerName returns the task definition name of the container id, or ""
+// if the task does not list it.
+func (t *ecsTask) containerName(id string) string {
+	if t == nil {
+		return ""
+	}
+
+	for _, ctr := range t.Containers {
+		if ctr.DockerID == id {
+			return ctr.Name
+		}
+	}
+
+	return ""
+}
+
+// containerImage returns the image of the container id, or "" if the task
+// does not list it.
+func (t *ecsTask) containerImage(id string) string {
+	if t == nil {
+		return ""
+	}
+
+	for _, ctr := range t.Containers {
+		if ctr.DockerID == id {
+			return ctr.Image
+		}
+	}
+
+	return ""
+}
+
+// arn returns the task ARN, or "" if t is nil.
+func (t *ecsTask) arn() string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Arn
+}
+
+// ecsTaskMetadataVariables are the environment variables holding the ECS task
+// metadata endpoint, newest version first.
+var ecsTaskMetadataVariables = []string{"ECS_CONTAINER_METADATA_URI_V4", "ECS_CONTAINER_METADATA_URI"}
+
+// getFargatePlatformVersion returns the Fargate platform version of the ECS
+// task, such as "1.4.0", from the task metadata endpoint, or "" if not
+// running on Fargate or the version is not reported.
+func getFargatePlatformVersion(c *config) string {
+	if !c.network || getAWSExecutionEnv() != awsECSFargate {
+		return ""
+	}
+
+	var endpoint string
+	for _, v := range ecsTaskMetadataVariables {
+		if endpoint = EnvironmentVariables[v]; endpoint != "" {
+			break
+		}
+	}
+
+	if endpoint == "" {
+		return ""
+	}
+
+	var version string
+
+	client := &http.Client{Timeout: c.probeTimeout()}
+	c.probe(func() bool {
+		resp, err := c.get(client, strings.TrimSuffix(endpoint, "/")+"/task")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return false
+		}
+
+		var task struct {
+			PlatformVersion string `json:"PlatformVersion"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&task); err != nil {
+			return false
+		}
+
+		version = strings.TrimPrefix(task.PlatformVersion, "v")
+		return true
+	})
+
+	return version
+}
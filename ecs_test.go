@@ -0,0 +1,150 @@
+package criprof
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testECSDockerID = "4b825dc642cb6eb9a060e54bf8d69288fbee4904d5f2f7b0c2c8c2f5a1e0b3c7"
+
+// withECSAgent serves body from a fake ECS agent introspection endpoint for
+// the duration of the test, and returns the number of requests it has served.
+func withECSAgent(t *testing.T, body string) *int32 {
+	t.Helper()
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Query().Get("dockerid") != testECSDockerID {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	old := ecsIntrospectionURL
+	ecsIntrospectionURL = srv.URL + "/v1/tasks"
+	t.Cleanup(func() { ecsIntrospectionURL = old })
+
+	return &requests
+}
+
+func TestGetECSTask(t *testing.T) {
+	withECSAgent(t, `{
+		"Arn": "arn:aws:ecs:us-east-1:123456789012:task/prod/0f1e2d3c4b5a",
+		"DesiredStatus": "RUNNING",
+		"Family": "web",
+		"Version": "7",
+		"Containers": [
+			{"DockerId": "`+testECSDockerID+`", "DockerName": "ecs-web-7-app-e4b1", "Image": "nginx:1.25", "Name": "app"}
+		]
+	}`)
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		network bool
+		arn     string
+		ctr     string
+		image   string
+	}{
+		{"ecs on ec2", map[string]string{"AWS_EXECUTION_ENV": "AWS_ECS_EC2"}, true, "arn:aws:ecs:us-east-1:123456789012:task/prod/0f1e2d3c4b5a", "app", "nginx:1.25"},
+		{"fargate", map[string]string{"AWS_EXECUTION_ENV": "AWS_ECS_FARGATE"}, true, "", "", ""},
+		{"network disabled", map[string]string{"AWS_EXECUTION_ENV": "AWS_ECS_EC2"}, false, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEnvironment(t, tt.env)
+
+			opts := []Option{WithTimeout(time.Second)}
+			if !tt.network {
+				opts = append(opts, WithoutNetwork())
+			}
+
+			task := getECSTask(newConfig(opts...), testECSDockerID)
+			if got := task.arn(); got != tt.arn {
+				t.Errorf("arn() = %q, want %q", got, tt.arn)
+			}
+			if got := task.containerName(testECSDockerID); got != tt.ctr {
+				t.Errorf("containerName() = %q, want %q", got, tt.ctr)
+			}
+			if got := task.containerImage(testECSDockerID); got != tt.image {
+				t.Errorf("containerImage() = %q, want %q", got, tt.image)
+			}
+		})
+	}
+}
+
+func TestGetFargatePlatformVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		execEnv  string
+		metadata string
+		want     string
+	}{
+		{"1.4.0", "AWS_ECS_FARGATE", `{"Cluster":"prod","LaunchType":"FARGATE","PlatformVersion":"1.4.0"}`, "1.4.0"},
+		{"1.3.0", "AWS_ECS_FARGATE", `{"Cluster":"prod","PlatformVersion":"1.3.0"}`, "1.3.0"},
+		{"not reported", "AWS_ECS_FARGATE", `{"Cluster":"prod"}`, ""},
+		{"ec2", "AWS_ECS_EC2", `{"Cluster":"prod","PlatformVersion":"1.4.0"}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v4/abc/task" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Write([]byte(tt.metadata))
+			}))
+			t.Cleanup(srv.Close)
+
+			withEnvironment(t, map[string]string{
+				"AWS_EXECUTION_ENV":             tt.execEnv,
+				"ECS_CONTAINER_METADATA_URI_V4": srv.URL + "/v4/abc",
+			})
+
+			if got := getFargatePlatformVersion(newConfig(WithTimeout(time.Second))); got != tt.want {
+				t.Errorf("getFargatePlatformVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetECSTaskInvalidID(t *testing.T) {
+	requests := withECSAgent(t, `{"Arn": "arn:aws:ecs:us-east-1:123456789012:task/prod/0f1e2d3c4b5a"}`)
+	withEnvironment(t, map[string]string{"AWS_EXECUTION_ENV": "AWS_ECS_EC2"})
+
+	c := newConfig(WithTimeout(time.Second))
+	for _, id := range []string{"", "undetermined", "4b825dc642cb"} {
+		if task := getECSTask(c, id); task != nil {
+			t.Errorf("getECSTask(%q) = %+v, want nil", id, task)
+		}
+	}
+
+	if n := atomic.LoadInt32(requests); n != 0 {
+		t.Errorf("getECSTask() made %d requests without a full container ID, want none", n)
+	}
+}
+
+func TestNewSkipsECSAgent(t *testing.T) {
+	requests := withECSAgent(t, `{"Arn": "arn:aws:ecs:us-east-1:123456789012:task/prod/0f1e2d3c4b5a"}`)
+	withEnvironment(t, map[string]string{"AWS_EXECUTION_ENV": "AWS_ECS_EC2"})
+	withCgroupFiles(t, "0::/system.slice/docker-"+testECSDockerID+".scope\n")
+
+	NewWithOptions(WithTimeout(time.Second))
+	if n := atomic.LoadInt32(requests); n != 0 {
+		t.Errorf("New() made %d ECS agent requests, want none", n)
+	}
+
+	Profile(context.Background(), WithTimeout(time.Second))
+	if n := atomic.LoadInt32(requests); n == 0 {
+		t.Error("Profile() made no ECS agent requests")
+	}
+}
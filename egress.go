@@ -0,0 +1,27 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+// defaultEgressAddr is dialed by WithEgressProbe when no address is given: a
+// public DNS resolver, which firewalls rarely block outright.
+const defaultEgressAddr = "1.1.1.1:53"
+
+// hasEgress returns true if a TCP connection to the address configured with
+// WithEgressProbe succeeds. It returns false without dialing unless the probe
+// was requested.
+func hasEgress(c *config) bool {
+	if c.egress == "" || !c.network {
+		return false
+	}
+
+	return c.probe(func() bool {
+		conn, err := c.dial(c.egress)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+
+		return true
+	})
+}
@@ -0,0 +1,48 @@
+package criprof
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHasEgress(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unreachable := closed.Addr().String()
+	closed.Close()
+
+	tests := []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{"reachable", []Option{WithEgressProbe(ln.Addr().String())}, true},
+		{"unreachable", []Option{WithEgressProbe(unreachable)}, false},
+		{"not requested", nil, false},
+		{"network disabled", []Option{WithEgressProbe(ln.Addr().String()), WithoutNetwork()}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfig(append([]Option{WithTimeout(time.Second)}, tt.opts...)...)
+			if got := hasEgress(c); got != tt.want {
+				t.Errorf("hasEgress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithEgressProbeDefault(t *testing.T) {
+	if got := newConfig(WithEgressProbe("")).egress; got != defaultEgressAddr {
+		t.Errorf("WithEgressProbe(\"\") addr = %q, want %q", got, defaultEgressAddr)
+	}
+}
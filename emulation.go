@@ -0,0 +1,129 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// binfmtMiscPath is where binfmt_misc lists the interpreters registered for
+// foreign executable formats.
+var binfmtMiscPath = "/proc/sys/fs/binfmt_misc"
+
+// unameArches maps GOARCH values to the machine names the kernel and QEMU use.
+var unameArches = map[string]string{
+	"386":     "i386",
+	"amd64":   "x86_64",
+	"arm":     "arm",
+	"arm64":   "aarch64",
+	"mips64":  "mips64",
+	"ppc64le": "ppc64le",
+	"riscv64": "riscv64",
+	"s390x":   "s390x",
+}
+
+// nativeMachines lists, for each GOARCH, the kernel machine names that run its
+// binaries natively, including 64-bit kernels running 32-bit binaries in
+// compat mode. A trailing "*" matches a prefix, as 32-bit ARM kernels report
+// their revision, such as armv7l.
+var nativeMachines = map[string][]string{
+	"386":     {"i386", "i486", "i586", "i686", "x86_64"},
+	"amd64":   {"x86_64"},
+	"arm":     {"arm*", "aarch64"},
+	"arm64":   {"aarch64"},
+	"mips64":  {"mips64"},
+	"ppc64le": {"ppc64le"},
+	"riscv64": {"riscv64"},
+	"s390x":   {"s390x"},
+}
+
+// isEmulated returns true if the process runs under user-mode emulation of a
+// foreign architecture, such as QEMU registered through binfmt_misc for
+// multi-arch builds. QEMU reports the emulated machine from uname, so the host
+// architecture is read from /proc/sys/kernel/arch, which it does not intercept.
+// On kernels without that file, an enabled QEMU handler for the process's own
+// architecture is taken as the sign, since none is registered for the native
+// one.
+func isEmulated() bool {
+	return isEmulatedArch(runtime.GOARCH)
+}
+
+// isEmulatedArch applies isEmulated to a process built for goarch.
+func isEmulatedArch(goarch string) bool {
+	arch, ok := unameArches[goarch]
+	if !ok {
+		return false
+	}
+
+	if host, err := ioutil.ReadFile(filepath.Join(procPath, "sys", "kernel", "arch")); err == nil {
+		return !isNativeMachine(goarch, strings.TrimSpace(string(host)))
+	}
+
+	return hasBinfmtHandler(arch)
+}
+
+// isNativeMachine returns true if a kernel reporting machine runs goarch
+// binaries without emulation.
+func isNativeMachine(goarch, machine string) bool {
+	for _, m := range nativeMachines[goarch] {
+		if m == machine || (strings.HasSuffix(m, "*") && strings.HasPrefix(machine, strings.TrimSuffix(m, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasBinfmtHandler returns true if an enabled binfmt_misc handler runs arch
+// executables through a QEMU user-mode interpreter.
+func hasBinfmtHandler(arch string) bool {
+	handlers, err := ioutil.ReadDir(binfmtMiscPath)
+	if err != nil {
+		return false
+	}
+
+	for _, h := range handlers {
+		if h.Name() == "register" || h.Name() == "status" {
+			continue
+		}
+
+		enabled, interpreter := readBinfmtHandler(filepath.Join(binfmtMiscPath, h.Name()))
+		if enabled && strings.HasPrefix(filepath.Base(interpreter), "qemu-"+arch) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readBinfmtHandler returns whether the binfmt_misc handler at path is enabled
+// and the interpreter it runs.
+func readBinfmtHandler(path string) (bool, string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, ""
+	}
+	defer f.Close()
+
+	var enabled bool
+	var interpreter string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "enabled":
+			enabled = true
+		case strings.HasPrefix(line, "interpreter "):
+			interpreter = strings.TrimPrefix(line, "interpreter ")
+		}
+	}
+
+	return enabled, interpreter
+}
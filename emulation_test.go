@@ -0,0 +1,57 @@
+package criprof
+
+import "testing"
+
+func withBinfmtMisc(t *testing.T, handlers map[string]string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "status", "enabled\n")
+	for name, contents := range handlers {
+		writeTestFile(t, dir, name, contents)
+	}
+
+	old := binfmtMiscPath
+	binfmtMiscPath = dir
+	t.Cleanup(func() { binfmtMiscPath = old })
+}
+
+func TestIsEmulatedArch(t *testing.T) {
+	const qemuAarch64 = "enabled\ninterpreter /usr/bin/qemu-aarch64-static\nflags: F\noffset 0\nmagic 7f454c460201010000000000000000000200b700\n"
+
+	tests := []struct {
+		name     string
+		hostArch string
+		handlers map[string]string
+		goarch   string
+		want     bool
+	}{
+		{"foreign arch", "x86_64\n", map[string]string{"qemu-aarch64": qemuAarch64}, "arm64", true},
+		{"native arch", "x86_64\n", map[string]string{"qemu-aarch64": qemuAarch64}, "amd64", false},
+		{"handler without kernel arch", "", map[string]string{"qemu-aarch64": qemuAarch64}, "arm64", true},
+		{"disabled handler", "", map[string]string{"qemu-aarch64": "disabled\ninterpreter /usr/bin/qemu-aarch64-static\n"}, "arm64", false},
+		{"no handler", "", nil, "arm64", false},
+		{"unknown arch", "x86_64\n", nil, "wasm", false},
+		{"386 in compat mode", "x86_64\n", nil, "386", false},
+		{"native 32-bit x86", "i686\n", nil, "386", false},
+		{"arm in compat mode", "aarch64\n", nil, "arm", false},
+		{"native armv7", "armv7l\n", nil, "arm", false},
+		{"native armv6", "armv6l\n", nil, "arm", false},
+		{"arm on x86_64", "x86_64\n", nil, "arm", true},
+		{"amd64 on aarch64", "aarch64\n", nil, "amd64", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t, testProcess{"1", "0", "app"})
+			if tt.hostArch != "" {
+				writeTestFile(t, dir, "sys/kernel/arch", tt.hostArch)
+			}
+			withBinfmtMisc(t, tt.handlers)
+
+			if got := isEmulatedArch(tt.goarch); got != tt.want {
+				t.Errorf("isEmulatedArch(%q) = %v, want %v", tt.goarch, got, tt.want)
+			}
+		})
+	}
+}
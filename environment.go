@@ -0,0 +1,138 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Environment classifications.
+const (
+	environmentContainer  = "container"  // OS-level container or sandbox
+	environmentServerless = "serverless" // Managed function or container platform
+	environmentVM         = "vm"         // Virtual machine without a container
+	environmentBareMetal  = "bare-metal" // Physical host without a container
+)
+
+// Isolation classes reported in Inventory.Isolation.
+const (
+	isolationVM        = "vm"        // Hardware-virtualized microVM
+	isolationSandboxed = "sandboxed" // Dedicated guest kernel or user-space kernel
+	isolationProcess   = "process"   // Namespaces and cgroups on the host kernel
+)
+
+// coldStartWindow is how soon after the container started detection must run
+// to be considered part of a cold start.
+const coldStartWindow = 10 * time.Second
+
+// hypervisorVendors are DMI sys_vendor and product_name substrings reported by
+// common hypervisors and cloud VMs.
+var hypervisorVendors = []string{
+	"QEMU",
+	"KVM",
+	"VMware",
+	"VirtualBox",
+	"innotek GmbH",
+	"Xen",
+	"Microsoft Corporation",
+	"Amazon EC2",
+	"Google Compute Engine",
+	"OpenStack",
+	"Parallels",
+	"BHYVE",
+}
+
+// getEnvironment classifies the environment as a container, serverless
+// platform, virtual machine or bare metal from the detected runtime and
+// scheduler and, when no container is found, the hypervisor signals.
+func getEnvironment(runtime, scheduler string) string {
+	container := runtime != runtimeUndetermined || IsContainer()
+
+	return classifyEnvironment(scheduler, container, container || isVirtualMachine())
+}
+
+// classifyEnvironment returns the environment class for the given signals.
+// Serverless platforms are containers or microVMs, so they take precedence.
+func classifyEnvironment(scheduler string, container, vm bool) string {
+	switch {
+	case scheduler == schedulerLambda || scheduler == schedulerCloudRun || scheduler == schedulerCloudRunJob || scheduler == schedulerAppRunner:
+		return environmentServerless
+	case container:
+		return environmentContainer
+	case vm:
+		return environmentVM
+	}
+
+	return environmentBareMetal
+}
+
+// isVirtualMachine returns true if the system reports a hypervisor.
+func isVirtualMachine() bool {
+	// Check the DMI vendor and product names for a known hypervisor.
+	vendor, _ := readDMI("sys_vendor")
+	product, _ := readDMI("product_name")
+	if isHypervisorDMI(vendor, product) {
+		return true
+	}
+
+	// Check if the CPU advertises the hypervisor feature flag.
+	cpuinfo, err := ioutil.ReadFile(filepath.Join(procPath, "cpuinfo"))
+	if err == nil && strings.Contains(string(cpuinfo), " hypervisor") {
+		return true
+	}
+
+	return false
+}
+
+// isHypervisorDMI returns true if the DMI vendor or product name belongs to a
+// known hypervisor.
+func isHypervisorDMI(vendor, product string) bool {
+	for _, h := range hypervisorVendors {
+		if strings.Contains(vendor, h) || strings.Contains(product, h) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isColdStart returns true if a serverless environment appears to be serving
+// its first invocation, judged by the container having started within
+// coldStartWindow. It is a heuristic: an Inventory built during a warm
+// invocation shortly after a cold one is also reported as cold, and a slow
+// initialization may exceed the window.
+func isColdStart(environment string, started *time.Time) bool {
+	if environment != environmentServerless || started == nil {
+		return false
+	}
+
+	return time.Since(*started) < coldStartWindow
+}
+
+// getIsolation returns the isolation class of the workload's runtime, or "" if
+// the runtime is undetermined. rktStage1 and lxdType refine runtimes that can
+// run either containers or VMs.
+func getIsolation(runtime, rktStage1, lxdType string) string {
+	switch runtime {
+	case runtimeUndetermined:
+		return ""
+	case runtimeFirecracker, runtimeIgnite:
+		return isolationVM
+	case runtimeGVisor, runtimeKata, runtimeWASM:
+		return isolationSandboxed
+	case runtimeRkt:
+		if rktStage1 == rktStage1KVM {
+			return isolationVM
+		}
+	case runtimeLXD:
+		if lxdType == "virtual-machine" {
+			return isolationVM
+		}
+	}
+
+	return isolationProcess
+}
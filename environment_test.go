@@ -0,0 +1,88 @@
+package criprof
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClassifyEnvironment(t *testing.T) {
+	tests := []struct {
+		name      string
+		scheduler string
+		vendor    string
+		product   string
+		container bool
+		want      string
+	}{
+		{"container", schedulerKubernetes, "", "", true, environmentContainer},
+		{"serverless", schedulerLambda, "", "", true, environmentServerless},
+		{"vm", schedulerUndetermined, "QEMU", "Standard PC (Q35 + ICH9, 2009)", false, environmentVM},
+		{"cloud vm", schedulerUndetermined, "Amazon EC2", "m5.large", false, environmentVM},
+		{"bare metal", schedulerUndetermined, "Dell Inc.", "PowerEdge R640", false, environmentBareMetal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := isHypervisorDMI(tt.vendor, tt.product)
+
+			if got := classifyEnvironment(tt.scheduler, tt.container, vm); got != tt.want {
+				t.Errorf("classifyEnvironment() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsColdStart(t *testing.T) {
+	fresh := time.Now().Add(-2 * time.Second)
+	warm := time.Now().Add(-5 * time.Minute)
+
+	tests := []struct {
+		name        string
+		environment string
+		started     *time.Time
+		want        bool
+	}{
+		{"serverless fresh", environmentServerless, &fresh, true},
+		{"serverless warm", environmentServerless, &warm, false},
+		{"serverless unknown start", environmentServerless, nil, false},
+		{"container fresh", environmentContainer, &fresh, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isColdStart(tt.environment, tt.started); got != tt.want {
+				t.Errorf("isColdStart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetIsolation(t *testing.T) {
+	tests := []struct {
+		runtime   string
+		rktStage1 string
+		lxdType   string
+		want      string
+	}{
+		{runtimeFirecracker, "", "", isolationVM},
+		{runtimeIgnite, "", "", isolationVM},
+		{runtimeRkt, rktStage1KVM, "", isolationVM},
+		{runtimeLXD, "", "virtual-machine", isolationVM},
+		{runtimeKata, "", "", isolationSandboxed},
+		{runtimeGVisor, "", "", isolationSandboxed},
+		{runtimeDocker, "", "", isolationProcess},
+		{runtimePodman, "", "", isolationProcess},
+		{runtimeContainerD, "", "", isolationProcess},
+		{runtimeRkt, rktStage1CoreOS, "", isolationProcess},
+		{runtimeLXD, "", "container", isolationProcess},
+		{runtimeUndetermined, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.runtime+tt.rktStage1+tt.lxdType, func(t *testing.T) {
+			if got := getIsolation(tt.runtime, tt.rktStage1, tt.lxdType); got != tt.want {
+				t.Errorf("getIsolation(%q, %q, %q) = %q, want %q", tt.runtime, tt.rktStage1, tt.lxdType, got, tt.want)
+			}
+		})
+	}
+}
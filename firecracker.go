@@ -0,0 +1,118 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Firecracker microVM metadata service endpoints. MMDS shares the link-local
+// address of cloud instance metadata services.
+var (
+	mmdsURL      = "http://169.254.169.254/"
+	mmdsTokenURL = "http://169.254.169.254/latest/api/token"
+)
+
+// isFirecracker returns true if running in a Firecracker microVM guest.
+// Firecracker has no PCI bus or SMBIOS tables; its devices are virtio-mmio
+// devices passed on the kernel command line.
+func isFirecracker() bool {
+	if _, err := readDMI("sys_vendor"); err == nil {
+		return false
+	}
+
+	for _, param := range readKernelCmdline() {
+		if strings.HasPrefix(param, "virtio_mmio.device=") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getMMDSKeys returns the sorted top-level keys of the Firecracker MMDS data
+// store, or nil if MMDS was not requested, the guest is not Firecracker, or no
+// store is configured. Only the keys are reported, since the values are
+// platform data that may include credentials. MMDS answers a JSON request for
+// / with the store as an object, where cloud metadata services answer with a
+// plain-text listing, so the response shape tells them apart.
+func getMMDSKeys(c *config, runtime string) []string {
+	if !c.mmds || !c.network || runtime != runtimeFirecracker {
+		return nil
+	}
+
+	var keys []string
+
+	client := &http.Client{Timeout: c.probeTimeout()}
+	c.probe(func() bool {
+		req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, mmdsURL, nil)
+		if err != nil {
+			return false
+		}
+		req.Header.Set("Accept", "application/json")
+
+		// MMDS version 2 requires a session token; version 1 ignores it.
+		if token := getMMDSToken(c.ctx, client); token != "" {
+			req.Header.Set("X-metadata-token", token)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return false
+		}
+
+		var store map[string]json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&store); err != nil {
+			// Not an MMDS store, so there is nothing to retry.
+			return true
+		}
+
+		keys = make([]string, 0, len(store))
+		for k := range store {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		return true
+	})
+
+	return keys
+}
+
+// getMMDSToken returns an MMDS version 2 session token, or "" if none is
+// issued.
+func getMMDSToken(ctx context.Context, client *http.Client) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, mmdsTokenURL, nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("X-metadata-token-ttl-seconds", "60")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	var b strings.Builder
+	if _, err := io.Copy(&b, io.LimitReader(resp.Body, 256)); err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,97 @@
+package criprof
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsFirecracker(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmdline string
+		dmi     map[string]string
+		want    bool
+	}{
+		{"guest", "console=ttyS0 reboot=k panic=1 pci=off virtio_mmio.device=4K@0xd0000000:5 root=/dev/vda", map[string]string{}, true},
+		{"qemu microvm", "console=ttyS0 virtio_mmio.device=4K@0xd0000000:5", map[string]string{"sys_vendor": "QEMU\n"}, false},
+		{"pci guest", "BOOT_IMAGE=/vmlinuz root=/dev/vda1 console=ttyS0", map[string]string{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t, testProcess{"1", "0", "init"})
+			writeTestFile(t, dir, "cmdline", tt.cmdline+"\n")
+			withDMI(t, tt.dmi)
+
+			if got := isFirecracker(); got != tt.want {
+				t.Errorf("isFirecracker() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func withMMDS(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	oldURL, oldToken := mmdsURL, mmdsTokenURL
+	mmdsURL, mmdsTokenURL = srv.URL+"/", srv.URL+"/latest/api/token"
+	t.Cleanup(func() { mmdsURL, mmdsTokenURL = oldURL, oldToken })
+}
+
+func TestGetMMDSKeys(t *testing.T) {
+	const store = `{"fly":{"app":"web","region":"ord"},"latest":{"meta-data":{"instance-id":"i-1"}}}`
+
+	v2 := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			w.Write([]byte("tok-123"))
+			return
+		}
+		if r.Header.Get("X-metadata-token") != "tok-123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(store))
+	}
+	v1 := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(store))
+	}
+	imds := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1.0\n2009-04-04\nlatest\n"))
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		opts    []Option
+		runtime string
+		want    []string
+	}{
+		{"mmds v2", v2, []Option{WithMMDS()}, runtimeFirecracker, []string{"fly", "latest"}},
+		{"mmds v1", v1, []Option{WithMMDS()}, runtimeFirecracker, []string{"fly", "latest"}},
+		{"cloud imds", imds, []Option{WithMMDS()}, runtimeFirecracker, nil},
+		{"not requested", v1, nil, runtimeFirecracker, nil},
+		{"not firecracker", v1, []Option{WithMMDS()}, runtimeDocker, nil},
+		{"network disabled", v1, []Option{WithMMDS(), WithoutNetwork()}, runtimeFirecracker, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withMMDS(t, tt.handler)
+
+			c := newConfig(append([]Option{WithTimeout(time.Second)}, tt.opts...)...)
+			if got := getMMDSKeys(c, tt.runtime); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getMMDSKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// gcpProduct is the DMI product name of Google Compute Engine VMs, including
+// GKE nodes.
+const gcpProduct = "Google Compute Engine"
+
+// gcpMetadataURL is the base URL of the Google Compute Engine metadata server.
+var gcpMetadataURL = "http://metadata.google.internal/computeMetadata/v1"
+
+// isGCE returns true if running on a Google Compute Engine VM.
+func isGCE() bool {
+	v, err := readDMI("product_name")
+	return err == nil && v == gcpProduct
+}
+
+// getGCPMetadata returns the value at path beneath gcpMetadataURL, such as
+// "instance/attributes/cluster-name". The metadata server is only queried on
+// Compute Engine, so other hosts are never probed.
+func getGCPMetadata(c *config, path string) (string, bool) {
+	if !c.network || !isGCE() {
+		return "", false
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, gcpMetadataURL+"/"+path, nil)
+	if err != nil {
+		return "", false
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var value string
+
+	client := &http.Client{Timeout: c.probeTimeout()}
+	ok := c.probe(func() bool {
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return false
+		}
+
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return false
+		}
+
+		value = strings.TrimSpace(string(b))
+		return true
+	})
+
+	return value, ok
+}
+
+// gcpIdentity is the project and instance reported by the metadata server.
+type gcpIdentity struct {
+	project  string
+	instance string
+}
+
+// getGCPIdentity returns the Google Cloud project ID and numeric instance ID
+// of the VM, or nil if not running on Compute Engine or the metadata server
+// does not answer.
+func getGCPIdentity(c *config) *gcpIdentity {
+	project, ok := getGCPMetadata(c, "project/project-id")
+	if !ok {
+		return nil
+	}
+
+	instance, _ := getGCPMetadata(c, "instance/id")
+
+	return &gcpIdentity{project: project, instance: instance}
+}
+
+// projectID returns the project ID, or "" if gi is nil.
+func (gi *gcpIdentity) projectID() string {
+	if gi == nil {
+		return ""
+	}
+
+	return gi.project
+}
+
+// instanceID returns the instance ID, or "" if gi is nil.
+func (gi *gcpIdentity) instanceID() string {
+	if gi == nil {
+		return ""
+	}
+
+	return gi.instance
+}
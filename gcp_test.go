@@ -0,0 +1,60 @@
+package criprof
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetGCPIdentity(t *testing.T) {
+	withGCPMetadata(t, map[string]string{
+		"/project/project-id": "shop-prod",
+		"/instance/id":        "4520031799277581759",
+	})
+
+	gi := getGCPIdentity(newConfig(WithTimeout(time.Second)))
+	if gi.projectID() != "shop-prod" || gi.instanceID() != "4520031799277581759" {
+		t.Errorf("getGCPIdentity() = %q, %q, want the metadata server's project and instance", gi.projectID(), gi.instanceID())
+	}
+
+	if got := getCloudProvider(); got != cloudGCP {
+		t.Errorf("getCloudProvider() = %q, want %q", got, cloudGCP)
+	}
+
+	if gi := getGCPIdentity(newConfig(WithoutNetwork())); gi != nil {
+		t.Errorf("getGCPIdentity() = %+v without network, want nil", gi)
+	}
+}
+
+func TestGetGCPIdentityNotGCE(t *testing.T) {
+	withGCPMetadata(t, map[string]string{"/project/project-id": "shop-prod"})
+	withDMI(t, map[string]string{"product_name": "Standard PC (Q35 + ICH9, 2009)\n"})
+
+	if gi := getGCPIdentity(newConfig(WithTimeout(time.Second))); gi != nil {
+		t.Errorf("getGCPIdentity() = %+v off Compute Engine, want nil", gi)
+	}
+
+	if got := getCloudProvider(); got != "" {
+		t.Errorf("getCloudProvider() = %q, want empty", got)
+	}
+}
+
+func TestNewSkipsGCPIdentity(t *testing.T) {
+	requests := withGCPMetadata(t, map[string]string{
+		"/project/project-id": "shop-prod",
+		"/instance/id":        "4520031799277581759",
+	})
+	withOverrides(t)
+	withEnvironment(t, map[string]string{overrideScheduler: "nomad"})
+
+	i := NewWithOptions(WithTimeout(time.Second))
+	if n := atomic.LoadInt32(requests); n != 0 || i.CloudProject != "" {
+		t.Errorf("New() made %d metadata requests and reported project %q, want none", n, i.CloudProject)
+	}
+
+	p := Profile(context.Background(), WithTimeout(time.Second))
+	if p.CloudProject != "shop-prod" || p.CloudInstanceID != "4520031799277581759" {
+		t.Errorf("Profile() = project %q, instance %q, want the metadata server's", p.CloudProject, p.CloudInstanceID)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Detectable accelerator vendors.
+const (
+	gpuNVIDIA = "nvidia" // NVIDIA GPU via the NVIDIA container toolkit
+	gpuAMD    = "amd"    // AMD GPU via ROCm
+	gpuTPU    = "tpu"    // Google Cloud TPU
+)
+
+// devPath is the mount point of the device filesystem.
+var devPath = "/dev"
+
+// getGPUVendor returns the vendor of the accelerators available to the
+// container, or "" if none are detected.
+func getGPUVendor() string {
+	if isNVIDIA() {
+		return gpuNVIDIA
+	}
+
+	// Check if the ROCm compute interface is exposed.
+	if _, err := os.Stat(filepath.Join(devPath, "kfd")); err == nil {
+		return gpuAMD
+	}
+
+	if isTPU() {
+		return gpuTPU
+	}
+
+	return ""
+}
+
+// isNVIDIA returns true if an NVIDIA GPU is exposed to the container. The
+// NVIDIA_VISIBLE_DEVICES variable is baked into CUDA base images whether or not
+// a GPU is attached, so the driver or a device node is required.
+func isNVIDIA() bool {
+	// Check if the NVIDIA driver is loaded.
+	if _, err := os.Stat(filepath.Join(procPath, "driver", "nvidia")); err == nil {
+		return true
+	}
+
+	// Check if NVIDIA device nodes have been injected.
+	if m, _ := filepath.Glob(filepath.Join(devPath, "nvidia[0-9]*")); len(m) > 0 {
+		return true
+	}
+
+	return false
+}
+
+// isTPU returns true if a Google Cloud TPU is attached.
+func isTPU() bool {
+	// Check if the TPU_NAME or TPU_ACCELERATOR_TYPE environment variable is
+	// set by the TPU runtime.
+	for _, v := range []string{"TPU_NAME", "TPU_ACCELERATOR_TYPE"} {
+		if _, ok := EnvironmentVariables[v]; ok {
+			return true
+		}
+	}
+
+	// Check if TPU chips are exposed as /dev/accel<N> device nodes.
+	if m, _ := filepath.Glob(filepath.Join(devPath, "accel[0-9]*")); len(m) > 0 {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,77 @@
+package criprof
+
+import "testing"
+
+// withDevTree points devPath at a temporary directory containing the given
+// device nodes for the duration of the test.
+func withDevTree(t *testing.T, devices ...string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, d := range devices {
+		writeTestFile(t, dir, d, "")
+	}
+
+	old := devPath
+	devPath = dir
+	t.Cleanup(func() { devPath = old })
+}
+
+func TestGetGPUVendorNVIDIA(t *testing.T) {
+	tests := []struct {
+		name    string
+		devices []string
+		driver  bool
+		env     map[string]string
+		want    string
+	}{
+		{"device nodes", []string{"nvidia0", "nvidiactl", "nvidia-uvm"}, false, nil, gpuNVIDIA},
+		{"driver", nil, true, nil, gpuNVIDIA},
+		{"visible devices with device nodes", []string{"nvidia0", "nvidiactl"}, false, map[string]string{"NVIDIA_VISIBLE_DEVICES": "all"}, gpuNVIDIA},
+		{"visible devices only", nil, false, map[string]string{"NVIDIA_VISIBLE_DEVICES": "all"}, ""},
+		{"none", []string{"null", "zero"}, false, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t)
+			if tt.driver {
+				writeTestFile(t, dir, "driver/nvidia/version", "NVRM version: NVIDIA UNIX x86_64 Kernel Module  535.104.05\n")
+			}
+			withDevTree(t, tt.devices...)
+			withEnvironment(t, tt.env)
+
+			if got := getGPUVendor(); got != tt.want {
+				t.Errorf("getGPUVendor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetGPUVendorAccelerators(t *testing.T) {
+	tests := []struct {
+		name    string
+		devices []string
+		env     map[string]string
+		want    string
+	}{
+		{"amd", []string{"kfd", "dri/card0", "dri/renderD128"}, nil, gpuAMD},
+		{"tpu env", nil, map[string]string{"TPU_NAME": "local"}, gpuTPU},
+		{"tpu devices", []string{"accel0", "accel1"}, nil, gpuTPU},
+		{"accel subsystem", []string{"accel/accel0"}, nil, ""},
+		{"render nodes only", []string{"dri/card0", "dri/renderD128"}, nil, ""},
+		{"nvidia wins", []string{"nvidia0", "dri/renderD128"}, nil, gpuNVIDIA},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withProcTree(t)
+			withDevTree(t, tt.devices...)
+			withEnvironment(t, tt.env)
+
+			if got := getGPUVendor(); got != tt.want {
+				t.Errorf("getGPUVendor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
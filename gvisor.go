@@ -0,0 +1,54 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// gvisorVersion is the fixed kernel build string gVisor's Sentry reports in
+// /proc/version.
+const gvisorVersion = "#1 SMP Sun Jan 10 15:06:54 PST 2016"
+
+// isGVisor returns true if running in a gVisor sandbox.
+func isGVisor() bool {
+	version, err := ioutil.ReadFile(filepath.Join(procPath, "version"))
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(string(version), gvisorVersion)
+}
+
+// getGVisorPlatform returns the platform gVisor intercepts system calls with,
+// such as "systrap", "ptrace" or "kvm", or "" if the runtime is not gVisor or
+// the platform cannot be seen. The sandbox hides it, so it is only read from a
+// runsc ancestor's --platform flag when the runsc process is visible.
+func getGVisorPlatform(runtime string) string {
+	if runtime != runtimeGVisor {
+		return ""
+	}
+
+	return parseRunscPlatform(ancestorCmdline("runsc"))
+}
+
+// parseRunscPlatform returns the value of the --platform flag in runsc's
+// arguments, in either the --platform=kvm or --platform kvm form.
+func parseRunscPlatform(args []string) string {
+	for i, arg := range args {
+		for _, flag := range []string{"--platform", "-platform"} {
+			if v := strings.TrimPrefix(arg, flag+"="); v != arg {
+				return v
+			}
+
+			if arg == flag && i+1 < len(args) {
+				return args[i+1]
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,55 @@
+package criprof
+
+import "testing"
+
+func TestIsGVisor(t *testing.T) {
+	dir := withProcTree(t, testProcess{"1", "0", "app"})
+
+	writeTestFile(t, dir, "version", "Linux version 4.4.0 #1 SMP Sun Jan 10 15:06:54 PST 2016\n")
+	if !isGVisor() {
+		t.Error("isGVisor() = false with the gVisor kernel version")
+	}
+
+	writeTestFile(t, dir, "version", "Linux version 6.1.0-18-amd64 (debian-kernel@lists.debian.org) #1 SMP PREEMPT_DYNAMIC Debian 6.1.76-1 (2024-02-01)\n")
+	if isGVisor() {
+		t.Error("isGVisor() = true with a Debian kernel version")
+	}
+}
+
+func TestParseRunscPlatform(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"equals", []string{"runsc", "--root=/run/containerd/runsc/k8s.io", "--platform=kvm", "boot"}, "kvm"},
+		{"separate", []string{"runsc", "-platform", "ptrace", "boot"}, "ptrace"},
+		{"default", []string{"runsc", "--root=/run/containerd/runsc/k8s.io", "boot"}, ""},
+		{"no runsc", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRunscPlatform(tt.args); got != tt.want {
+				t.Errorf("parseRunscPlatform(%v) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetGVisorPlatform(t *testing.T) {
+	dir := withProcTree(t,
+		testProcess{"4211", "4102", "app"},
+		testProcess{"4102", "1", "runsc-sandbox"},
+		testProcess{"1", "0", "systemd"},
+	)
+	writeTestFile(t, dir, "4102/cmdline", "runsc-sandbox\x00--platform=systrap\x00boot\x00")
+
+	if got := getGVisorPlatform(runtimeGVisor); got != "systrap" {
+		t.Errorf("getGVisorPlatform() = %q, want %q", got, "systrap")
+	}
+
+	if got := getGVisorPlatform(runtimeDocker); got != "" {
+		t.Errorf("getGVisorPlatform(docker) = %q, want empty", got)
+	}
+}
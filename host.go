@@ -0,0 +1,186 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Detectable host operating systems.
+const (
+	hostOSFlatcar      = "flatcar"       // Flatcar Container Linux
+	hostOSFedoraCoreOS = "fedora-coreos" // Fedora CoreOS
+	hostOSLinuxKit     = "linuxkit"      // LinuxKit, as used by Docker Desktop
+)
+
+// osReleasePaths are the os-release files checked for the host OS, in order.
+// /host/etc is the conventional hostPath mount for node agents.
+var osReleasePaths = []string{
+	"/host/etc/os-release",
+	"/etc/os-release",
+	"/usr/lib/os-release",
+}
+
+// getWSLVersion returns the Windows Subsystem for Linux version (1 or 2) the
+// application is running under, or 0 if not running under WSL. WSL is not a
+// container runtime, so it is reported separately from Inventory.Runtime.
+func getWSLVersion() int {
+	// Check the kernel release, which carries the Microsoft build suffix.
+	if v := parseWSLVersion(readKernelRelease()); v != 0 {
+		return v
+	}
+
+	// Fall back to /proc/version for kernels with a custom release string.
+	if version, err := ioutil.ReadFile(filepath.Join(procPath, "version")); err == nil {
+		return parseWSLVersion(string(version))
+	}
+
+	return 0
+}
+
+// readKernelRelease returns the running kernel's release string, such as
+// "5.15.49-linuxkit", or "" if it cannot be read.
+func readKernelRelease() string {
+	osrelease, err := ioutil.ReadFile(filepath.Join(procPath, "sys", "kernel", "osrelease"))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(osrelease))
+}
+
+// parseWSLVersion returns the WSL version indicated by a kernel release or
+// /proc/version string. WSL2 kernels are built as "microsoft-standard" (and
+// newer ones carry "WSL2"), while WSL1 reports a "Microsoft" suffix.
+func parseWSLVersion(s string) int {
+	if strings.Contains(s, "WSL2") || strings.Contains(s, "microsoft-standard") {
+		return 2
+	}
+
+	if strings.Contains(strings.ToLower(s), "microsoft") {
+		return 1
+	}
+
+	return 0
+}
+
+// getHostOS returns the container-optimised host operating system, if
+// detected.
+func getHostOS() string {
+	for _, p := range osReleasePaths {
+		f, err := os.Open(p)
+		if err != nil {
+			continue
+		}
+
+		release := parseOSRelease(f)
+		f.Close()
+
+		if h := hostOSFromRelease(release); h != "" {
+			return h
+		}
+	}
+
+	// Check if the kernel is a LinuxKit build, as the host's os-release is
+	// rarely mounted into containers on Docker Desktop.
+	if version, err := ioutil.ReadFile(filepath.Join(procPath, "version")); err == nil {
+		if strings.Contains(string(version), "linuxkit") {
+			return hostOSLinuxKit
+		}
+	}
+
+	return ""
+}
+
+// parseOSRelease parses os-release(5) KEY=value lines, removing quotes.
+func parseOSRelease(r io.Reader) map[string]string {
+	release := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		release[kv[0]] = strings.Trim(kv[1], `"'`)
+	}
+
+	return release
+}
+
+// hostOSFromRelease maps parsed os-release fields to a host OS.
+func hostOSFromRelease(release map[string]string) string {
+	switch {
+	case release["ID"] == "flatcar":
+		return hostOSFlatcar
+	case release["ID"] == "fedora" && release["VARIANT_ID"] == "coreos":
+		return hostOSFedoraCoreOS
+	case release["ID"] == "linuxkit":
+		return hostOSLinuxKit
+	}
+
+	return ""
+}
+
+// nestedVirtPaths are the KVM module parameters reporting whether nested
+// virtualization is enabled, for Intel and AMD respectively.
+var nestedVirtPaths = []string{
+	"/sys/module/kvm_intel/parameters/nested",
+	"/sys/module/kvm_amd/parameters/nested",
+}
+
+// getNestedVirt returns true if KVM nested virtualization is enabled, which
+// Kata and Firecracker require when the host is itself a VM.
+func getNestedVirt() bool {
+	for _, p := range nestedVirtPaths {
+		nested, err := ioutil.ReadFile(p)
+		if err != nil {
+			continue
+		}
+
+		if parseNestedParam(string(nested)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseNestedParam interprets a KVM nested parameter, which is "Y"/"N" on
+// current kernels and "1"/"0" on older ones.
+func parseNestedParam(s string) bool {
+	switch strings.TrimSpace(s) {
+	case "Y", "y", "1":
+		return true
+	}
+
+	return false
+}
+
+// clockSourcePath reports the kernel's active clock source. Paravirtual
+// sources such as kvm-clock, hyperv_clocksource_tsc_page and xen corroborate
+// virtualization.
+var clockSourcePath = "/sys/devices/system/clocksource/clocksource0/current_clocksource"
+
+// getClockSource returns the kernel's current clock source, such as "tsc" or
+// "kvm-clock", or "" if it cannot be read.
+func getClockSource() string {
+	source, err := ioutil.ReadFile(clockSourcePath)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(source))
+}
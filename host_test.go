@@ -0,0 +1,155 @@
+package criprof
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseWSLVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		osrelease string
+		want      int
+	}{
+		{"wsl1", "4.4.0-19041-Microsoft\n", 1},
+		{"wsl2", "5.15.90.1-microsoft-standard-WSL2\n", 2},
+		{"wsl2 legacy", "4.19.104-microsoft-standard\n", 2},
+		{"linux", "6.1.0-18-amd64\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseWSLVersion(tt.osrelease); got != tt.want {
+				t.Errorf("parseWSLVersion(%q) = %d, want %d", tt.osrelease, got, tt.want)
+			}
+		})
+	}
+}
+
+const testFlatcarRelease = `NAME="Flatcar Container Linux by Kinvolk"
+ID=flatcar
+ID_LIKE=coreos
+VERSION=3510.2.1
+VERSION_ID=3510.2.1
+PRETTY_NAME="Flatcar Container Linux by Kinvolk 3510.2.1 (Oklo)"
+`
+
+const testFedoraCoreOSRelease = `NAME="Fedora Linux"
+VERSION="38.20230709.3.0 (CoreOS)"
+ID=fedora
+VERSION_ID=38
+VARIANT="CoreOS"
+VARIANT_ID=coreos
+`
+
+func TestHostOSFromRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		release string
+		want    string
+	}{
+		{"flatcar", testFlatcarRelease, hostOSFlatcar},
+		{"fedora coreos", testFedoraCoreOSRelease, hostOSFedoraCoreOS},
+		{"linuxkit", "PRETTY_NAME=\"Docker Desktop\"\nID=linuxkit\n", hostOSLinuxKit},
+		{"debian", "ID=debian\nVERSION_ID=\"12\"\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := parseOSRelease(strings.NewReader(tt.release))
+			if got := hostOSFromRelease(release); got != tt.want {
+				t.Errorf("hostOSFromRelease() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetHostOSLinuxKitVersion(t *testing.T) {
+	old := osReleasePaths
+	osReleasePaths = nil
+	t.Cleanup(func() { osReleasePaths = old })
+
+	dir := withProcTree(t, testProcess{"1", "0", "app"})
+
+	writeTestFile(t, dir, "version", "Linux version 5.15.49-linuxkit (root@buildkitsandbox) (gcc (Alpine 10.2.1_pre1) 10.2.1 20201203, GNU ld (GNU Binutils) 2.35.2) #1 SMP Tue Sep 13 07:51:46 UTC 2022\n")
+	if got := getHostOS(); got != hostOSLinuxKit {
+		t.Errorf("getHostOS() = %q, want %q", got, hostOSLinuxKit)
+	}
+
+	writeTestFile(t, dir, "version", "Linux version 6.1.0-18-amd64 (debian-kernel@lists.debian.org) #1 SMP PREEMPT_DYNAMIC Debian 6.1.76-1 (2024-02-01)\n")
+	if got := getHostOS(); got != "" {
+		t.Errorf("getHostOS() = %q, want empty", got)
+	}
+}
+
+func TestGetNestedVirt(t *testing.T) {
+	tests := []struct {
+		name  string
+		intel string
+		amd   string
+		want  bool
+	}{
+		{"intel enabled", "Y\n", "", true},
+		{"amd enabled", "", "1\n", true},
+		{"disabled", "N\n", "0\n", false},
+		{"no kvm", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			var paths []string
+			for i, v := range []string{tt.intel, tt.amd} {
+				p := filepath.Join(dir, fmt.Sprintf("nested%d", i))
+				if v != "" {
+					if err := ioutil.WriteFile(p, []byte(v), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				paths = append(paths, p)
+			}
+
+			old := nestedVirtPaths
+			nestedVirtPaths = paths
+			t.Cleanup(func() { nestedVirtPaths = old })
+
+			if got := getNestedVirt(); got != tt.want {
+				t.Errorf("getNestedVirt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetClockSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"kvm", "kvm-clock\n", "kvm-clock"},
+		{"tsc", "tsc\n", "tsc"},
+		{"missing", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			p := filepath.Join(dir, "current_clocksource")
+			if tt.source != "" {
+				writeTestFile(t, dir, "current_clocksource", tt.source)
+			}
+
+			old := clockSourcePath
+			clockSourcePath = p
+			t.Cleanup(func() { clockSourcePath = old })
+
+			if got := getClockSource(); got != tt.want {
+				t.Errorf("getClockSource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
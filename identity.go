@@ -0,0 +1,61 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import "strings"
+
+// Workload identity mechanisms reported in Inventory.WorkloadIdentity.
+const (
+	workloadIdentityGKE   = "gke-wi"   // GKE Workload Identity
+	workloadIdentityIRSA  = "irsa"     // EKS IAM Roles for Service Accounts
+	workloadIdentityAzure = "azure-wi" // Azure AD Workload Identity
+)
+
+// getWorkloadIdentityFromEnv returns the workload identity mechanism
+// recognised from the variables its admission webhook injects, IRSA or Azure
+// Workload Identity, or "" if neither is found.
+func getWorkloadIdentityFromEnv(scheduler string) string {
+	if !isKubernetesScheduler(scheduler) {
+		return ""
+	}
+
+	if EnvironmentVariables["AWS_ROLE_ARN"] != "" && EnvironmentVariables["AWS_WEB_IDENTITY_TOKEN_FILE"] != "" {
+		return workloadIdentityIRSA
+	}
+
+	if EnvironmentVariables["AZURE_FEDERATED_TOKEN_FILE"] != "" {
+		return workloadIdentityAzure
+	}
+
+	return ""
+}
+
+// getWorkloadIdentity returns the workload identity mechanism that federates
+// the pod's service account with a cloud identity, or "" if none is found.
+// IRSA and Azure Workload Identity are recognised from the environment. GKE
+// Workload Identity replaces the node's metadata server with one that lists
+// the cluster's workload identity pool, <project>.svc.id.goog, among the
+// instance's service accounts.
+func getWorkloadIdentity(c *config, scheduler string) string {
+	if !isKubernetesScheduler(scheduler) {
+		return ""
+	}
+
+	if wi := getWorkloadIdentityFromEnv(scheduler); wi != "" {
+		return wi
+	}
+
+	accounts, ok := getGCPMetadata(c, "instance/service-accounts/")
+	if !ok {
+		return ""
+	}
+
+	for _, a := range strings.Fields(accounts) {
+		if strings.HasSuffix(strings.TrimSuffix(a, "/"), ".svc.id.goog") {
+			return workloadIdentityGKE
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,51 @@
+package criprof
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetWorkloadIdentity(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		accounts  string
+		scheduler string
+		want      string
+	}{
+		{"irsa", map[string]string{
+			"AWS_ROLE_ARN":                "arn:aws:iam::123456789012:role/web",
+			"AWS_WEB_IDENTITY_TOKEN_FILE": "/var/run/secrets/eks.amazonaws.com/serviceaccount/token",
+		}, "", schedulerKubernetes, workloadIdentityIRSA},
+		{"irsa on eks fargate", map[string]string{
+			"AWS_ROLE_ARN":                "arn:aws:iam::123456789012:role/web",
+			"AWS_WEB_IDENTITY_TOKEN_FILE": "/var/run/secrets/eks.amazonaws.com/serviceaccount/token",
+		}, "", schedulerEKSFargate, workloadIdentityIRSA},
+		{"role without token", map[string]string{"AWS_ROLE_ARN": "arn:aws:iam::123456789012:role/web"}, "", schedulerKubernetes, ""},
+		{"azure", map[string]string{
+			"AZURE_CLIENT_ID":            "0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b",
+			"AZURE_FEDERATED_TOKEN_FILE": "/var/run/secrets/azure/tokens/azure-identity-token",
+		}, "", schedulerKubernetes, workloadIdentityAzure},
+		{"gke", nil, "default/\nshop-prod.svc.id.goog/\n", schedulerKubernetes, workloadIdentityGKE},
+		{"gke node service account", nil, "default/\n123456789-compute@developer.gserviceaccount.com/\n", schedulerKubernetes, ""},
+		{"not kubernetes", map[string]string{
+			"AWS_ROLE_ARN":                "arn:aws:iam::123456789012:role/web",
+			"AWS_WEB_IDENTITY_TOKEN_FILE": "/var/run/secrets/eks.amazonaws.com/serviceaccount/token",
+		}, "", schedulerECS, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEnvironment(t, tt.env)
+			if tt.accounts != "" {
+				withGCPMetadata(t, map[string]string{"/instance/service-accounts/": tt.accounts})
+			} else {
+				withDMI(t, map[string]string{})
+			}
+
+			if got := getWorkloadIdentity(newConfig(WithTimeout(time.Second)), tt.scheduler); got != tt.want {
+				t.Errorf("getWorkloadIdentity() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ package criprof
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
 // Detectable image formats
@@ -18,7 +19,12 @@ const (
 )
 
 // getImageFormat returns the format of the container image currently running.
-func getImageFormat() (string, error) {
+func getImageFormat(c *config) (string, error) {
+	// Check if the image format has been forced by CRIPROF_FORCE_IMAGE_FORMAT.
+	if v, ok := override(c, overrideImageFormat); ok {
+		return v, nil
+	}
+
 	// Check if Docker format
 	if _, err := isDockerFormat(); err == nil {
 		return formatDocker, nil
@@ -46,19 +52,53 @@ func getImageFormat() (string, error) {
 }
 
 func isDockerFormat() (bool, error) {
-	_, err := os.Stat("/.dockerinit")
+	_, err := os.Stat(dockerInitPath)
 	if err == nil {
 		return true, nil
 	} else if !os.IsNotExist(err) {
-		return false, fmt.Errorf("failed to check /.dockerinit file: %v", err)
+		return false, fmt.Errorf("failed to check %s file: %v", dockerInitPath, err)
 	}
 
-	_, err = os.Stat("/.dockerenv")
+	_, err = os.Stat(dockerEnvPath)
 	if err == nil {
 		return true, nil
 	} else if !os.IsNotExist(err) {
-		return false, fmt.Errorf("failed to check /.dockerenv file: %v", err)
+		return false, fmt.Errorf("failed to check %s file: %v", dockerEnvPath, err)
 	}
 
 	return false, nil
 }
+
+// shellPaths and packageManagerPaths are the tools a distroless image omits.
+var (
+	shellPaths = []string{
+		"bin/sh",
+		"bin/bash",
+		"bin/busybox",
+		"usr/bin/sh",
+		"usr/bin/bash",
+	}
+	packageManagerPaths = []string{
+		"usr/bin/apt-get",
+		"usr/bin/dpkg",
+		"sbin/apk",
+		"usr/bin/yum",
+		"usr/bin/dnf",
+		"usr/bin/microdnf",
+		"usr/bin/rpm",
+	}
+)
+
+// isDistroless returns true if the filesystem at root looks like a distroless
+// or scratch image, having neither a shell nor a package manager.
+func isDistroless(root string) bool {
+	for _, paths := range [][]string{shellPaths, packageManagerPaths} {
+		for _, p := range paths {
+			if _, err := os.Lstat(filepath.Join(root, p)); err == nil {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -7,6 +7,32 @@ import (
 func BenchmarkGetImageFormat(b *testing.B) {
 	// Run getImageFormat function b.N times.
 	for i := 0; i < b.N; i++ {
-		getImageFormat()
+		getImageFormat(newConfig())
+	}
+}
+
+func TestIsDistroless(t *testing.T) {
+	distroless := t.TempDir()
+	writeTestFile(t, distroless, "app", "")
+	writeTestFile(t, distroless, "etc/passwd", "nonroot:x:65532:65532::/home/nonroot:/sbin/nologin\n")
+	writeTestFile(t, distroless, "var/lib/dpkg/status.d/base", "Package: base-files\n")
+
+	if !isDistroless(distroless) {
+		t.Error("isDistroless() = false for a tree without a shell or package manager")
+	}
+
+	debian := t.TempDir()
+	writeTestFile(t, debian, "bin/sh", "")
+	writeTestFile(t, debian, "usr/bin/apt-get", "")
+
+	if isDistroless(debian) {
+		t.Error("isDistroless() = true for a tree with a shell")
+	}
+
+	alpine := t.TempDir()
+	writeTestFile(t, alpine, "sbin/apk", "")
+
+	if isDistroless(alpine) {
+		t.Error("isDistroless() = true for a tree with a package manager")
 	}
 }
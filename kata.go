@@ -0,0 +1,80 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kata Containers hypervisor backends.
+const (
+	kataQEMU            = "qemu" // QEMU (default)
+	kataFirecracker     = "fc"   // Firecracker
+	kataCloudHypervisor = "clh"  // Cloud Hypervisor
+)
+
+// kataMarkerPath is the directory the Kata agent creates in the guest VM.
+var kataMarkerPath = "/run/kata-containers"
+
+// isKata returns true if running in a Kata Containers guest VM.
+func isKata() bool {
+	// Check if the Kata agent's runtime directory exists.
+	if _, err := os.Stat(kataMarkerPath); err == nil {
+		return true
+	}
+
+	// Check if the guest kernel was booted with Kata agent parameters.
+	for _, param := range readKernelCmdline() {
+		if strings.HasPrefix(param, "agent.") || param == "systemd.unit=kata-containers.target" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getKataHypervisor returns the hypervisor backing a Kata Containers guest, or
+// "" if the runtime is not Kata.
+func getKataHypervisor(runtime string) string {
+	if runtime != runtimeKata {
+		return ""
+	}
+
+	vendor, _ := readDMI("sys_vendor")
+
+	return classifyKataHypervisor(readKernelCmdline(), vendor)
+}
+
+// classifyKataHypervisor infers the Kata hypervisor. Firecracker provides no
+// PCI bus, so its guests boot with pci=off and expose no DMI tables, while
+// QEMU and Cloud Hypervisor identify themselves as the DMI system vendor.
+func classifyKataHypervisor(params []string, vendor string) string {
+	switch {
+	case vendor == "QEMU":
+		return kataQEMU
+	case vendor == "Cloud Hypervisor":
+		return kataCloudHypervisor
+	}
+
+	for _, param := range params {
+		if param == "pci=off" {
+			return kataFirecracker
+		}
+	}
+
+	return ""
+}
+
+// readKernelCmdline returns the parameters the kernel was booted with.
+func readKernelCmdline() []string {
+	cmdline, err := ioutil.ReadFile(filepath.Join(procPath, "cmdline"))
+	if err != nil {
+		return nil
+	}
+
+	return strings.Fields(string(cmdline))
+}
@@ -0,0 +1,51 @@
+package criprof
+
+import "testing"
+
+func TestIsKata(t *testing.T) {
+	old := kataMarkerPath
+	kataMarkerPath = t.TempDir() + "/missing"
+	t.Cleanup(func() { kataMarkerPath = old })
+
+	dir := withProcTree(t, testProcess{"1", "0", "kata-agent"})
+
+	writeTestFile(t, dir, "cmdline", "tsc=reliable no_timer_check console=hvc0 root=/dev/pmem0p1 systemd.unit=kata-containers.target agent.log_vport=1025\n")
+	if !isKata() {
+		t.Error("isKata() = false with Kata agent kernel parameters")
+	}
+
+	writeTestFile(t, dir, "cmdline", "BOOT_IMAGE=/vmlinuz root=/dev/sda1 ro quiet\n")
+	if isKata() {
+		t.Error("isKata() = true without Kata markers")
+	}
+}
+
+func TestGetKataHypervisor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmdline string
+		dmi     map[string]string
+		want    string
+	}{
+		{"qemu", "console=hvc0 agent.log_vport=1025", map[string]string{"sys_vendor": "QEMU\n"}, kataQEMU},
+		{"firecracker", "console=ttyS0 reboot=k panic=1 pci=off agent.log_vport=1025", map[string]string{}, kataFirecracker},
+		{"cloud hypervisor", "console=hvc0 agent.log_vport=1025", map[string]string{"sys_vendor": "Cloud Hypervisor\n"}, kataCloudHypervisor},
+		{"unknown", "console=hvc0 agent.log_vport=1025", map[string]string{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t, testProcess{"1", "0", "kata-agent"})
+			writeTestFile(t, dir, "cmdline", tt.cmdline+"\n")
+			withDMI(t, tt.dmi)
+
+			if got := getKataHypervisor(runtimeKata); got != tt.want {
+				t.Errorf("getKataHypervisor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := getKataHypervisor(runtimeDocker); got != "" {
+		t.Errorf("getKataHypervisor(docker) = %q, want empty", got)
+	}
+}
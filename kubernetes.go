@@ -0,0 +1,349 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// podInfoPath is the conventional mount point of a Kubernetes Downward API
+// volume exposing the pod's labels, annotations and resources.
+var podInfoPath = "/etc/podinfo"
+
+// nodeNameVariables are the environment variables commonly populated with the
+// node name via the Downward API (spec.nodeName).
+var nodeNameVariables = []string{"NODE_NAME", "MY_NODE_NAME", "KUBE_NODE_NAME"}
+
+// getNodeName returns the Kubernetes node name if exposed to the pod, or "".
+func getNodeName() string {
+	for _, v := range nodeNameVariables {
+		if n := EnvironmentVariables[v]; n != "" {
+			return n
+		}
+	}
+
+	return ""
+}
+
+// readPodInfo returns the key/value pairs of a Downward API file, such as
+// "labels" or "annotations", beneath podInfoPath.
+func readPodInfo(name string) map[string]string {
+	f, err := os.Open(filepath.Join(podInfoPath, name))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	return parseDownwardAPI(f)
+}
+
+// parseDownwardAPI parses the key="value" lines the Downward API writes for
+// labels and annotations.
+func parseDownwardAPI(r io.Reader) map[string]string {
+	values := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		v, err := strconv.Unquote(kv[1])
+		if err != nil {
+			v = kv[1]
+		}
+
+		values[kv[0]] = v
+	}
+
+	return values
+}
+
+// Downward API file names conventionally used for projected resource requests.
+var (
+	cpuRequestFiles    = []string{"cpu_request", "requests.cpu"}
+	memoryRequestFiles = []string{"mem_request", "memory_request", "requests.memory"}
+)
+
+// getResourceRequest returns the resource request projected into the first of
+// files found beneath podInfoPath, or 0 if none is. The value is in the units
+// of the volume's divisor: cores (rounded up) or millicores for CPU, and bytes
+// by default for memory.
+func getResourceRequest(files []string) int64 {
+	for _, name := range files {
+		v, err := ioutil.ReadFile(filepath.Join(podInfoPath, name))
+		if err != nil {
+			continue
+		}
+
+		n, err := strconv.ParseInt(strings.TrimSpace(string(v)), 10, 64)
+		if err != nil {
+			return 0
+		}
+
+		return n
+	}
+
+	return 0
+}
+
+// isEKSFargate returns true if the Kubernetes pod is running on AWS Fargate
+// through EKS. ECS tasks on Fargate expose a task metadata endpoint and
+// execution environment, which EKS pods do not, so their presence rules EKS
+// out.
+func isEKSFargate() bool {
+	// Check if the ECS markers are set, indicating ECS.
+	if isECS() {
+		return false
+	}
+
+	// Check if the node name follows the fargate-<ip> convention.
+	if strings.HasPrefix(getNodeName(), "fargate-") {
+		return true
+	}
+
+	// Check if the Fargate profile label was applied to the pod.
+	if _, ok := readPodInfo("labels")["eks.amazonaws.com/fargate-profile"]; ok {
+		return true
+	}
+
+	return false
+}
+
+// isEphemeralContainer returns true if running as a Kubernetes ephemeral
+// container, as started by kubectl debug --target. These join the target
+// container's process namespace, so PID 1 is the target's workload: in another
+// mount namespace, inside the same pod's cgroup, and neither the host's init
+// nor the pod's pause process.
+func isEphemeralContainer() bool {
+	// Check if the KUBERNETES_SERVICE_HOST environment variable is set.
+	if _, ok := EnvironmentVariables["KUBERNETES_SERVICE_HOST"]; !ok {
+		return false
+	}
+
+	same, err := sameNamespace(
+		filepath.Join(procPath, "self", "ns", "mnt"),
+		filepath.Join(procPath, "1", "ns", "mnt"),
+	)
+	if err != nil || same {
+		return false
+	}
+
+	switch processComm("1") {
+	case "", "systemd", "init", "pause":
+		return false
+	}
+
+	cgroup, err := ioutil.ReadFile(filepath.Join(procPath, "1", "cgroup"))
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(string(cgroup), "kubepods")
+}
+
+// isPodSandbox returns true if PID 1 is the pause process that holds a
+// Kubernetes pod sandbox's namespaces, as seen by containers in a pod with
+// shareProcessNamespace enabled.
+func isPodSandbox() bool {
+	return processComm("1") == "pause"
+}
+
+// hasPauseAncestor returns true if an ancestor of the process is the pause
+// process that holds a Kubernetes pod sandbox's namespaces, as when the pod
+// shares its process namespace. It confirms a kubelet-managed pod without the
+// service account token, as with automountServiceAccountToken: false.
+func hasPauseAncestor() bool {
+	for _, comm := range processChain(maxAncestors) {
+		if comm == "pause" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Restart policies reported in Inventory.RestartPolicy, named as in the
+// Kubernetes pod spec.
+const (
+	restartAlways    = "Always"
+	restartOnFailure = "OnFailure"
+	restartNever     = "Never"
+)
+
+// restartPolicyVariables are the environment variables conventionally used to
+// expose the restart policy to the workload.
+var restartPolicyVariables = []string{"RESTART_POLICY", "POD_RESTART_POLICY"}
+
+// restartPolicyFiles are the Downward API file names conventionally used for a
+// projected restart policy annotation.
+var restartPolicyFiles = []string{"restart_policy", "restartPolicy"}
+
+// getRestartPolicy returns the workload's restart policy where it has been
+// exposed through the environment or a Downward API volume, or "" if
+// unavailable. Neither Kubernetes nor Nomad expose it by default.
+func getRestartPolicy() string {
+	for _, v := range restartPolicyVariables {
+		if p := parseRestartPolicy(EnvironmentVariables[v]); p != "" {
+			return p
+		}
+	}
+
+	for _, name := range restartPolicyFiles {
+		v, err := ioutil.ReadFile(filepath.Join(podInfoPath, name))
+		if err != nil {
+			continue
+		}
+
+		return parseRestartPolicy(string(v))
+	}
+
+	return ""
+}
+
+// parseRestartPolicy normalizes a Kubernetes or Docker restart policy name to
+// one of the Kubernetes policies, or "" if unrecognized.
+func parseRestartPolicy(v string) string {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "always", "unless-stopped":
+		return restartAlways
+	case "onfailure", "on-failure":
+		return restartOnFailure
+	case "never", "no":
+		return restartNever
+	}
+
+	return ""
+}
+
+// Directories where the kubelet exposes container logs on the node, visible
+// to pods that mount them.
+var (
+	podLogsPath       = "/var/log/pods"
+	containerLogsPath = "/var/log/containers"
+)
+
+// serviceAccountNamespacePath holds the pod's namespace alongside its service
+// account token.
+var serviceAccountNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// getPodNamespace returns the pod's namespace from the Downward API variable or
+// the service account mount, or "".
+func getPodNamespace() string {
+	if ns := EnvironmentVariables["POD_NAMESPACE"]; ns != "" {
+		return ns
+	}
+
+	ns, err := ioutil.ReadFile(serviceAccountNamespacePath)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(ns))
+}
+
+// getLogPath returns where the kubelet writes this container's logs, if the
+// node's log directories are mounted into the pod: the
+// /var/log/containers/<pod>_<namespace>_<container>-<id>.log symlink when the
+// container ID is known, otherwise the
+// /var/log/pods/<namespace>_<pod>_<uid>/<container> directory. The pod name
+// defaults to the hostname, and the container name comes from CONTAINER_NAME
+// or, failing that, the pod's only container. It returns "" if no path exists.
+func getLogPath(scheduler, hostname, id string) string {
+	if !isKubernetesScheduler(scheduler) {
+		return ""
+	}
+
+	ns := getPodNamespace()
+	pod := EnvironmentVariables["POD_NAME"]
+	if pod == "" {
+		pod = hostname
+	}
+
+	if ns == "" || pod == "" {
+		return ""
+	}
+
+	if validIDMatch.MatchString(id) && len(id) == 64 {
+		matches, _ := filepath.Glob(filepath.Join(containerLogsPath, pod+"_"+ns+"_*-"+id+".log"))
+		if len(matches) > 0 {
+			return matches[0]
+		}
+	}
+
+	dirs, _ := filepath.Glob(filepath.Join(podLogsPath, ns+"_"+pod+"_*"))
+	if len(dirs) != 1 {
+		return ""
+	}
+
+	if name := EnvironmentVariables["CONTAINER_NAME"]; name != "" {
+		p := filepath.Join(dirs[0], name)
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+			return p
+		}
+
+		return ""
+	}
+
+	containers, err := ioutil.ReadDir(dirs[0])
+	if err != nil || len(containers) != 1 || !containers[0].IsDir() {
+		return ""
+	}
+
+	return filepath.Join(dirs[0], containers[0].Name())
+}
+
+// gracePeriodVariables are the environment variables conventionally used to
+// expose the pod's terminationGracePeriodSeconds to the workload.
+var gracePeriodVariables = []string{"TERMINATION_GRACE_PERIOD", "TERMINATION_GRACE_PERIOD_SECONDS"}
+
+// gracePeriodFiles are the Downward API file names conventionally used for a
+// projected grace period annotation.
+var gracePeriodFiles = []string{"termination_grace_period", "terminationGracePeriodSeconds"}
+
+// getTerminationGracePeriod returns how many seconds the workload has between
+// SIGTERM and SIGKILL where it has been exposed through the environment or a
+// Downward API volume, or 0 if unavailable.
+func getTerminationGracePeriod() int64 {
+	for _, v := range gracePeriodVariables {
+		if d := parseGracePeriod(EnvironmentVariables[v]); d > 0 {
+			return int64(d / time.Second)
+		}
+	}
+
+	for _, name := range gracePeriodFiles {
+		v, err := ioutil.ReadFile(filepath.Join(podInfoPath, name))
+		if err != nil {
+			continue
+		}
+
+		return int64(parseGracePeriod(string(v)) / time.Second)
+	}
+
+	return 0
+}
+
+// parseGracePeriod parses a grace period given in whole seconds, as in the pod
+// spec, or as a Go duration such as "30s".
+func parseGracePeriod(v string) time.Duration {
+	v = strings.TrimSpace(v)
+
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return d
+	}
+
+	return 0
+}
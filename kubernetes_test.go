@@ -0,0 +1,271 @@
+package criprof
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withPodInfo points podInfoPath at a temporary Downward API volume holding
+// the given files for the duration of the test.
+func withPodInfo(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, contents := range files {
+		writeTestFile(t, dir, name, contents)
+	}
+
+	old := podInfoPath
+	podInfoPath = dir
+	t.Cleanup(func() { podInfoPath = old })
+}
+
+func TestParseDownwardAPI(t *testing.T) {
+	labels := parseDownwardAPI(strings.NewReader("app=\"web\"\npod-template-hash=\"7d4b9c\"\nnote=\"a \\\"quoted\\\" value\"\n"))
+
+	if labels["app"] != "web" {
+		t.Errorf("labels[app] = %q, want %q", labels["app"], "web")
+	}
+
+	if labels["note"] != `a "quoted" value` {
+		t.Errorf("labels[note] = %q, want %q", labels["note"], `a "quoted" value`)
+	}
+}
+
+func TestIsEKSFargate(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		labels string
+		want   bool
+	}{
+		{"node name", map[string]string{"NODE_NAME": "fargate-ip-10-0-1-23.ec2.internal"}, "", true},
+		{"profile label", nil, "eks.amazonaws.com/fargate-profile=\"default\"\n", true},
+		{"ecs fargate", map[string]string{"NODE_NAME": "fargate-ip-10-0-1-23.ec2.internal", "ECS_CONTAINER_METADATA_URI_V4": "http://169.254.170.2/v4/abc"}, "", false},
+		{"eks on ec2", map[string]string{"NODE_NAME": "ip-10-0-1-23.ec2.internal"}, "app=\"web\"\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEnvironment(t, tt.env)
+			withPodInfo(t, map[string]string{"labels": tt.labels})
+
+			if got := isEKSFargate(); got != tt.want {
+				t.Errorf("isEKSFargate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsEphemeralContainer(t *testing.T) {
+	const podCgroup = "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod7c1e.slice/cri-containerd-4f3a.scope\n"
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		init    string
+		initMnt string
+		cgroup  string
+		want    bool
+	}{
+		{"debug target", map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1"}, "nginx", "mnt:[4026532401]", podCgroup, true},
+		{"own process namespace", map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1"}, "nginx", "mnt:[4026532282]", podCgroup, false},
+		{"shared pod namespace", map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1"}, "pause", "mnt:[4026532401]", podCgroup, false},
+		{"hostPID", map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1"}, "systemd", "mnt:[4026531841]", "0::/init.scope\n", false},
+		{"outside Kubernetes", map[string]string{}, "nginx", "mnt:[4026532401]", podCgroup, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEnvironment(t, tt.env)
+			dir := withProcTree(t,
+				testProcess{"12", "0", "sh"},
+				testProcess{"1", "0", tt.init},
+			)
+			writeTestFile(t, dir, "1/cgroup", tt.cgroup)
+			writeTestNamespace(t, dir, "12/ns/mnt", "mnt:[4026532282]")
+			writeTestNamespace(t, dir, "1/ns/mnt", tt.initMnt)
+
+			if got := isEphemeralContainer(); got != tt.want {
+				t.Errorf("isEphemeralContainer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPodSandbox(t *testing.T) {
+	withProcTree(t,
+		testProcess{"14", "0", "app"},
+		testProcess{"1", "0", "pause"},
+	)
+
+	if !isPodSandbox() {
+		t.Error("isPodSandbox() = false with pause as PID 1")
+	}
+
+	withProcTree(t, testProcess{"1", "0", "app"})
+
+	if isPodSandbox() {
+		t.Error("isPodSandbox() = true with the app as PID 1")
+	}
+}
+
+func TestHasPauseAncestor(t *testing.T) {
+	withProcTree(t,
+		testProcess{"23", "14", "app"},
+		testProcess{"14", "1", "sh"},
+		testProcess{"1", "0", "pause"},
+	)
+
+	if !hasPauseAncestor() {
+		t.Error("hasPauseAncestor() = false with pause in the ancestry")
+	}
+
+	withEnvironment(t, map[string]string{})
+	if got := getScheduler(newConfig(WithoutNetwork())); got != schedulerKubernetes {
+		t.Errorf("getScheduler() = %q, want %q from the pause ancestor", got, schedulerKubernetes)
+	}
+
+	withEnvironment(t, map[string]string{"ECS_CONTAINER_METADATA_URI_V4": "http://169.254.170.2/v4/4f3a9c2b"})
+	if got := getScheduler(newConfig(WithoutNetwork())); got != schedulerECS {
+		t.Errorf("getScheduler() = %q, want %q over the pause ancestor", got, schedulerECS)
+	}
+
+	withProcTree(t,
+		testProcess{"23", "1", "app"},
+		testProcess{"1", "0", "tini"},
+	)
+
+	if hasPauseAncestor() {
+		t.Error("hasPauseAncestor() = true without pause in the ancestry")
+	}
+}
+
+func TestGetResourceRequest(t *testing.T) {
+	withPodInfo(t, map[string]string{
+		"labels":       "app=\"web\"\n",
+		"cpu_request":  "250\n",
+		"mem_request":  "134217728\n",
+		"cpu_limit":    "1000\n",
+		"requests.bad": "lots\n",
+	})
+
+	if got := getResourceRequest(cpuRequestFiles); got != 250 {
+		t.Errorf("getResourceRequest(cpu) = %d, want 250", got)
+	}
+
+	if got := getResourceRequest(memoryRequestFiles); got != 134217728 {
+		t.Errorf("getResourceRequest(memory) = %d, want 134217728", got)
+	}
+
+	if got := getResourceRequest([]string{"requests.bad"}); got != 0 {
+		t.Errorf("getResourceRequest(malformed) = %d, want 0", got)
+	}
+
+	withPodInfo(t, map[string]string{})
+
+	if got := getResourceRequest(cpuRequestFiles); got != 0 {
+		t.Errorf("getResourceRequest(cpu) = %d without podinfo, want 0", got)
+	}
+}
+
+func TestGetRestartPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		podinfo map[string]string
+		want    string
+	}{
+		{"env", map[string]string{"RESTART_POLICY": "OnFailure"}, nil, restartOnFailure},
+		{"docker name", map[string]string{"POD_RESTART_POLICY": "unless-stopped"}, nil, restartAlways},
+		{"podinfo", nil, map[string]string{"restart_policy": "Never\n"}, restartNever},
+		{"env wins", map[string]string{"RESTART_POLICY": "Always"}, map[string]string{"restart_policy": "Never\n"}, restartAlways},
+		{"unrecognized", map[string]string{"RESTART_POLICY": "sometimes"}, nil, ""},
+		{"unavailable", nil, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEnvironment(t, tt.env)
+			withPodInfo(t, tt.podinfo)
+
+			if got := getRestartPolicy(); got != tt.want {
+				t.Errorf("getRestartPolicy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetLogPath(t *testing.T) {
+	const id = "9b2f0a7c1d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f90"
+
+	dir := t.TempDir()
+	podDir := "shop_web-7d4b9c-x2k8p_5f0c2e8a-3b1d-4c6e-9a7f-2d8b0e1c4a6f"
+	writeTestFile(t, dir, "pods/"+podDir+"/app/0.log", "")
+	writeTestFile(t, dir, "pods/shop_db-0_1a2b3c4d-0000-4c6e-9a7f-2d8b0e1c4a6f/postgres/0.log", "")
+	writeTestFile(t, dir, "pods/shop_db-0_1a2b3c4d-0000-4c6e-9a7f-2d8b0e1c4a6f/exporter/0.log", "")
+	writeTestFile(t, dir, "containers/web-7d4b9c-x2k8p_shop_app-"+id+".log", "")
+
+	oldPods, oldContainers := podLogsPath, containerLogsPath
+	podLogsPath, containerLogsPath = filepath.Join(dir, "pods"), filepath.Join(dir, "containers")
+	t.Cleanup(func() { podLogsPath, containerLogsPath = oldPods, oldContainers })
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		scheduler string
+		hostname  string
+		id        string
+		want      string
+	}{
+		{"container symlink", map[string]string{"POD_NAMESPACE": "shop"}, schedulerKubernetes, "web-7d4b9c-x2k8p", id, filepath.Join(dir, "containers", "web-7d4b9c-x2k8p_shop_app-"+id+".log")},
+		{"only container", map[string]string{"POD_NAMESPACE": "shop"}, schedulerKubernetes, "web-7d4b9c-x2k8p", "undetermined", filepath.Join(dir, "pods", podDir, "app")},
+		{"named container", map[string]string{"POD_NAMESPACE": "shop", "POD_NAME": "db-0", "CONTAINER_NAME": "postgres"}, schedulerKubernetes, "db-0", "", filepath.Join(dir, "pods", "shop_db-0_1a2b3c4d-0000-4c6e-9a7f-2d8b0e1c4a6f", "postgres")},
+		{"ambiguous container", map[string]string{"POD_NAMESPACE": "shop"}, schedulerKubernetes, "db-0", "", ""},
+		{"unknown namespace", nil, schedulerKubernetes, "web-7d4b9c-x2k8p", id, ""},
+		{"eks fargate", map[string]string{"POD_NAMESPACE": "shop"}, schedulerEKSFargate, "web-7d4b9c-x2k8p", id, filepath.Join(dir, "containers", "web-7d4b9c-x2k8p_shop_app-"+id+".log")},
+		{"not kubernetes", map[string]string{"POD_NAMESPACE": "shop"}, schedulerNomad, "web-7d4b9c-x2k8p", id, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEnvironment(t, tt.env)
+
+			oldNS := serviceAccountNamespacePath
+			serviceAccountNamespacePath = filepath.Join(dir, "namespace")
+			t.Cleanup(func() { serviceAccountNamespacePath = oldNS })
+
+			if got := getLogPath(tt.scheduler, tt.hostname, tt.id); got != tt.want {
+				t.Errorf("getLogPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTerminationGracePeriod(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		podinfo map[string]string
+		want    int64
+	}{
+		{"env seconds", map[string]string{"TERMINATION_GRACE_PERIOD_SECONDS": "30"}, nil, 30},
+		{"env duration", map[string]string{"TERMINATION_GRACE_PERIOD": "1m30s"}, nil, 90},
+		{"podinfo", nil, map[string]string{"termination_grace_period": "45\n"}, 45},
+		{"zero", map[string]string{"TERMINATION_GRACE_PERIOD": "0"}, nil, 0},
+		{"malformed", map[string]string{"TERMINATION_GRACE_PERIOD": "soon"}, nil, 0},
+		{"unavailable", nil, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEnvironment(t, tt.env)
+			withPodInfo(t, tt.podinfo)
+
+			if got := getTerminationGracePeriod(); got != tt.want {
+				t.Errorf("getTerminationGracePeriod() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
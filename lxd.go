@@ -0,0 +1,54 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+)
+
+// lxdSocketPath is the LXD guest API socket, /dev/lxd, available in LXD
+// instances with security.devlxd enabled.
+var lxdSocketPath = "/dev/lxd/sock"
+
+// getLXDInstanceType returns the LXD instance type, "container" or
+// "virtual-machine", reported by the guest API, or "" if the runtime is not
+// LXD, network probes are disabled or the API is unavailable.
+func getLXDInstanceType(c *config, runtime string) string {
+	if !c.network || runtime != runtimeLXD {
+		return ""
+	}
+
+	if _, err := os.Stat(lxdSocketPath); err != nil {
+		return ""
+	}
+
+	var instanceType string
+
+	client := newUnixClient(c, lxdSocketPath)
+	c.probe(func() bool {
+		resp, err := c.get(client, "http://lxd/1.0")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return false
+		}
+
+		var info struct {
+			InstanceType string `json:"instance_type"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			return false
+		}
+
+		instanceType = info.InstanceType
+		return true
+	})
+
+	return instanceType
+}
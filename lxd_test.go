@@ -0,0 +1,97 @@
+package criprof
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// withLXDSocket serves the LXD guest API /1.0 response body on a fake
+// /dev/lxd socket for the duration of the test.
+func withLXDSocket(t *testing.T, body string) {
+	t.Helper()
+
+	p := filepath.Join(t.TempDir(), "sock")
+	l, err := net.Listen("unix", p)
+	if err != nil {
+		t.Skipf("cannot listen on a unix socket: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1.0" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	old := lxdSocketPath
+	lxdSocketPath = p
+	t.Cleanup(func() { lxdSocketPath = old })
+}
+
+func TestGetLXDInstanceType(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"container", `{"api_version":"1.0","instance_type":"container","location":"node1","state":"Started"}`, "container"},
+		{"virtual machine", `{"api_version":"1.0","instance_type":"virtual-machine","location":"node1","state":"Started"}`, "virtual-machine"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withLXDSocket(t, tt.body)
+			c := newConfig(WithTimeout(time.Second))
+
+			if got := getLXDInstanceType(c, runtimeLXD); got != tt.want {
+				t.Errorf("getLXDInstanceType() = %q, want %q", got, tt.want)
+			}
+
+			if got := getLXDInstanceType(c, runtimeDocker); got != "" {
+				t.Errorf("getLXDInstanceType(docker) = %q, want empty", got)
+			}
+		})
+	}
+}
+
+func TestGetLXDInstanceTypeWithoutNetwork(t *testing.T) {
+	withLXDSocket(t, `{"api_version":"1.0","instance_type":"container"}`)
+
+	if got := getLXDInstanceType(newConfig(WithoutNetwork()), runtimeLXD); got != "" {
+		t.Errorf("getLXDInstanceType() = %q with network probes disabled, want empty", got)
+	}
+}
+
+func TestGetLXDInstanceTypeClosesConnections(t *testing.T) {
+	withLXDSocket(t, `{"api_version":"1.0","instance_type":"container"}`)
+	c := newConfig(WithTimeout(time.Second))
+
+	// Warm up the server so its listener goroutines are in the baseline.
+	getLXDInstanceType(c, runtimeLXD)
+	baseline := runtime.NumGoroutine()
+
+	for n := 0; n < 5; n++ {
+		if got := getLXDInstanceType(c, runtimeLXD); got != "container" {
+			t.Fatalf("getLXDInstanceType() = %q, want container", got)
+		}
+	}
+
+	// Connection goroutines exit asynchronously once the socket closes.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := runtime.NumGoroutine(); n > baseline {
+		t.Errorf("%d goroutines after five probes, want no more than the %d before", n, baseline)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Service meshes reported in Inventory.ServiceMesh.
+const (
+	meshIstio   = "istio"   // Envoy sidecar injected by Istio
+	meshLinkerd = "linkerd" // linkerd2-proxy sidecar
+)
+
+// meshProxyPorts are the ports a mesh's sidecar proxy listens on to receive
+// traffic redirected to it by the mesh's iptables rules.
+var meshProxyPorts = []struct {
+	mesh  string
+	ports []int
+}{
+	{meshIstio, []int{15001, 15006}},
+	{meshLinkerd, []int{4140, 4143}},
+}
+
+// tcpListen is the state of a listening socket in /proc/net/tcp.
+const tcpListen = "0A"
+
+// getServiceMesh returns the service mesh whose sidecar proxy shares the
+// pod's network namespace, or "" if none is found. The proxy's redirect rules
+// are not readable without CAP_NET_ADMIN, but the ports they redirect to are
+// visible as listening sockets to every container in the pod.
+func getServiceMesh() string {
+	listening := make(map[int]bool)
+	for _, name := range []string{"tcp", "tcp6"} {
+		f, err := os.Open(filepath.Join(procPath, "self", "net", name))
+		if err != nil {
+			continue
+		}
+
+		for _, port := range parseListeningPorts(f) {
+			listening[port] = true
+		}
+		f.Close()
+	}
+
+	for _, m := range meshProxyPorts {
+		found := true
+		for _, port := range m.ports {
+			if !listening[port] {
+				found = false
+				break
+			}
+		}
+
+		if found {
+			return m.mesh
+		}
+	}
+
+	return ""
+}
+
+// parseListeningPorts returns the local ports of listening sockets in
+// /proc/net/tcp or /proc/net/tcp6 contents read from r.
+func parseListeningPorts(r io.Reader) []int {
+	var ports []int
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[3] != tcpListen {
+			continue
+		}
+
+		i := strings.LastIndexByte(fields[1], ':')
+		if i < 0 {
+			continue
+		}
+
+		port, err := strconv.ParseUint(fields[1][i+1:], 16, 16)
+		if err != nil {
+			continue
+		}
+
+		ports = append(ports, int(port))
+	}
+
+	return ports
+}
@@ -0,0 +1,62 @@
+package criprof
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const procNetTCPHeader = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+
+func procNetTCPLine(sl int, port int, state string) string {
+	return fmt.Sprintf("%4d: 00000000:%04X 00000000:0000 %s 00000000:00000000 00:00000000 00000000  1337        0 %d 1 0000000000000000 100 0 0 10 0\n", sl, port, state, 1000+sl)
+}
+
+func TestParseListeningPorts(t *testing.T) {
+	tcp := procNetTCPHeader +
+		procNetTCPLine(0, 15001, tcpListen) +
+		procNetTCPLine(1, 8080, tcpListen) +
+		procNetTCPLine(2, 15006, "01")
+
+	if got, want := parseListeningPorts(strings.NewReader(tcp)), []int{15001, 8080}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseListeningPorts() = %v, want %v", got, want)
+	}
+}
+
+func TestGetServiceMesh(t *testing.T) {
+	tests := []struct {
+		name  string
+		ports []int
+		want  string
+	}{
+		{"istio", []int{15001, 15006, 15090, 8080}, meshIstio},
+		{"linkerd", []int{4140, 4143, 4191, 8080}, meshLinkerd},
+		{"partial", []int{15001, 8080}, ""},
+		{"none", []int{8080}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tcp := procNetTCPHeader
+			for i, port := range tt.ports {
+				tcp += procNetTCPLine(i, port, tcpListen)
+			}
+
+			dir := withProcTree(t, testProcess{"7", "1", "app"})
+			writeTestFile(t, dir, "7/net/tcp", tcp)
+
+			if got := getServiceMesh(); got != tt.want {
+				t.Errorf("getServiceMesh() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetServiceMeshUnreadable(t *testing.T) {
+	withProcTree(t, testProcess{"7", "1", "app"})
+
+	if got := getServiceMesh(); got != "" {
+		t.Errorf("getServiceMesh() = %q, want empty", got)
+	}
+}
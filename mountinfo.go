@@ -0,0 +1,448 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Mount classifications reported in MountInfo.Type.
+const (
+	mountTmpfs   = "tmpfs"   // Memory-backed, lost on restart
+	mountOverlay = "overlay" // Container root filesystem upperdir, lost on removal
+	mountBind    = "bind"    // Bind mount of a host path
+	mountPVC     = "pvc"     // Kubernetes volume
+)
+
+// MountInfo describes the mount backing a writable path.
+type MountInfo struct {
+	Path       string `json:"path"`
+	Mountpoint string `json:"mountpoint"`
+	Type       string `json:"type"`
+	FSType     string `json:"fs_type"`
+	Options    string `json:"options"`
+}
+
+// mountEntry is a single line of /proc/self/mountinfo.
+type mountEntry struct {
+	Root         string
+	Mountpoint   string
+	Options      string
+	FSType       string
+	Source       string
+	SuperOptions string
+}
+
+// mountInfoCache memoizes the parsed mount table, which several detectors
+// share, for the duration of a detection pass. Entries are keyed by file path.
+var mountInfoCache = struct {
+	sync.Mutex
+	values map[string]mountInfoValue
+}{values: make(map[string]mountInfoValue)}
+
+// mountInfoValue is a cached readMountInfo result.
+type mountInfoValue struct {
+	entries []mountEntry
+	err     error
+}
+
+// resetMountInfoCache discards the memoized mount table so the next pass
+// observes the current mounts.
+func resetMountInfoCache() {
+	mountInfoCache.Lock()
+	mountInfoCache.values = make(map[string]mountInfoValue)
+	mountInfoCache.Unlock()
+}
+
+// readMountInfo returns the parsed mount table of the current process. The
+// table is read at most once per detection pass.
+func readMountInfo() ([]mountEntry, error) {
+	p := filepath.Join(procPath, "self", "mountinfo")
+
+	mountInfoCache.Lock()
+	defer mountInfoCache.Unlock()
+
+	if c, ok := mountInfoCache.values[p]; ok {
+		return c.entries, c.err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		mountInfoCache.values[p] = mountInfoValue{err: err}
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := parseMountInfo(f)
+	mountInfoCache.values[p] = mountInfoValue{entries: entries, err: err}
+
+	return entries, err
+}
+
+// parseMountInfo parses the mountinfo format described in proc(5). Malformed
+// lines are skipped.
+func parseMountInfo(r io.Reader) ([]mountEntry, error) {
+	var entries []mountEntry
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		// Optional fields are terminated by a single hyphen.
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+
+		if sep == -1 || len(fields) < sep+3 {
+			continue
+		}
+
+		e := mountEntry{
+			Root:       unescapeMountPath(fields[3]),
+			Mountpoint: unescapeMountPath(fields[4]),
+			Options:    fields[5],
+			FSType:     fields[sep+1],
+			Source:     fields[sep+2],
+		}
+
+		if len(fields) > sep+3 {
+			e.SuperOptions = fields[sep+3]
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes (\040 for space, etc.) the
+// kernel uses for paths in mountinfo.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// findMount returns the entry whose mountpoint most specifically contains
+// path. Later entries win ties, as they shadow earlier mounts.
+func findMount(entries []mountEntry, path string) (mountEntry, bool) {
+	var best mountEntry
+	found := false
+
+	for _, e := range entries {
+		if !pathHasPrefix(path, e.Mountpoint) {
+			continue
+		}
+
+		if !found || len(e.Mountpoint) >= len(best.Mountpoint) {
+			best = e
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+// pathHasPrefix returns true if path is dir or is beneath it.
+func pathHasPrefix(path, dir string) bool {
+	if dir == "/" || path == dir {
+		return true
+	}
+
+	return strings.HasPrefix(path, dir+"/")
+}
+
+// classifyMount returns whether a mount is tmpfs, overlay, a bind of a host
+// path or a Kubernetes volume. Other mounts are reported by filesystem type.
+func classifyMount(e mountEntry) string {
+	switch {
+	case e.FSType == "tmpfs":
+		return mountTmpfs
+	case e.FSType == "overlay":
+		return mountOverlay
+	case strings.Contains(e.Root, "/volumes/kubernetes.io~"):
+		return mountPVC
+	case e.Root != "/":
+		return mountBind
+	}
+
+	return e.FSType
+}
+
+// getMounts classifies the mounts backing common writable paths: /tmp, /data
+// and the working directory.
+func getMounts() []MountInfo {
+	entries, err := readMountInfo()
+	if err != nil {
+		return nil
+	}
+
+	paths := []string{"/tmp", "/data"}
+	if wd, err := os.Getwd(); err == nil {
+		paths = append(paths, wd)
+	}
+
+	var existing []string
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			existing = append(existing, p)
+		}
+	}
+
+	return classifyMounts(entries, existing)
+}
+
+// classifyMounts returns a MountInfo for the mount backing each path.
+func classifyMounts(entries []mountEntry, paths []string) []MountInfo {
+	var mounts []MountInfo
+
+	for _, p := range paths {
+		e, ok := findMount(entries, p)
+		if !ok {
+			continue
+		}
+
+		mounts = append(mounts, MountInfo{
+			Path:       p,
+			Mountpoint: e.Mountpoint,
+			Type:       classifyMount(e),
+			FSType:     e.FSType,
+			Options:    e.Options,
+		})
+	}
+
+	return mounts
+}
+
+// getShmSize returns the size limit in bytes of the tmpfs mounted at /dev/shm,
+// or 0 if it is not mounted or its size is not fixed.
+func getShmSize() int64 {
+	entries, err := readMountInfo()
+	if err != nil {
+		return 0
+	}
+
+	return shmSizeFromMounts(entries)
+}
+
+// shmSizeFromMounts returns the size of the /dev/shm tmpfs in entries.
+func shmSizeFromMounts(entries []mountEntry) int64 {
+	for _, e := range entries {
+		if e.Mountpoint != "/dev/shm" || e.FSType != "tmpfs" {
+			continue
+		}
+
+		for _, opt := range strings.Split(e.SuperOptions, ",") {
+			if v := strings.TrimPrefix(opt, "size="); v != opt {
+				return parseTmpfsSize(v)
+			}
+		}
+	}
+
+	return 0
+}
+
+// parseTmpfsSize parses a tmpfs size= option, a byte count with an optional
+// k, m or g suffix. Sizes given as a percentage of memory return 0.
+func parseTmpfsSize(v string) int64 {
+	if v == "" {
+		return 0
+	}
+
+	mult := int64(1)
+
+	switch strings.ToLower(v[len(v)-1:]) {
+	case "k":
+		mult = 1 << 10
+	case "m":
+		mult = 1 << 20
+	case "g":
+		mult = 1 << 30
+	}
+
+	if mult != 1 {
+		v = v[:len(v)-1]
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+
+	return n * mult
+}
+
+// maskedPaths are the /proc and /sys paths container runtimes mask, by binding
+// /dev/null or an empty tmpfs over them, unless the container is privileged.
+var maskedPaths = []string{
+	"/proc/kcore",
+	"/proc/keys",
+	"/proc/timer_list",
+	"/proc/acpi",
+	"/proc/scsi",
+	"/sys/firmware",
+}
+
+// readOnlyPaths are the kernel interfaces runtimes remount read-only.
+var readOnlyPaths = []string{
+	"/proc/sys",
+	"/proc/sysrq-trigger",
+	"/sys",
+}
+
+// isProcMasked returns true if parts of /proc or /sys are masked or read-only,
+// as in unprivileged containers.
+func isProcMasked() bool {
+	entries, err := readMountInfo()
+	if err != nil {
+		return false
+	}
+
+	return procMaskedFromMounts(entries)
+}
+
+// procMaskedFromMounts returns true if entries mount over a masked path or
+// mount a kernel interface read-only.
+func procMaskedFromMounts(entries []mountEntry) bool {
+	for _, e := range entries {
+		for _, p := range maskedPaths {
+			if e.Mountpoint == p {
+				return true
+			}
+		}
+
+		for _, p := range readOnlyPaths {
+			if e.Mountpoint == p && hasMountOption(e.Options, "ro") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasMountOption returns true if the comma-separated options include opt.
+func hasMountOption(options, opt string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == opt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scratchPaths returns the directories an application might use for scratch
+// space, in order of preference: the working directory, $TMPDIR, then the
+// conventional temporary directories.
+func scratchPaths() []string {
+	var paths []string
+
+	if wd, err := os.Getwd(); err == nil {
+		paths = append(paths, wd)
+	}
+
+	if tmp := EnvironmentVariables["TMPDIR"]; tmp != "" {
+		paths = append(paths, tmp)
+	}
+
+	return append(paths, "/tmp", "/var/tmp")
+}
+
+// getScratchDir returns the first of scratchPaths the process may write to,
+// and the filesystem type of the mount backing it. Writability is judged from
+// the mode bits, owner and mount options; nothing is written.
+func getScratchDir() (string, string) {
+	entries, _ := readMountInfo()
+
+	return scratchDirFrom(scratchPaths(), os.Stat, entries, os.Geteuid())
+}
+
+// scratchDirFrom returns the first of paths that stat reports as a directory
+// writable by euid and that is not on a read-only mount in entries, with the
+// mount's filesystem type. The type is "" if the mount table is unavailable.
+func scratchDirFrom(paths []string, stat func(string) (os.FileInfo, error), entries []mountEntry, euid int) (string, string) {
+	for _, p := range paths {
+		fi, err := stat(p)
+		if err != nil || !fi.IsDir() {
+			continue
+		}
+
+		owner, ok := fileOwner(fi)
+		if !canWrite(fi.Mode(), owner, ok, euid) {
+			continue
+		}
+
+		m, found := findMount(entries, p)
+		if found && hasMountOption(m.Options, "ro") {
+			continue
+		}
+
+		return p, m.FSType
+	}
+
+	return "", ""
+}
+
+// cgroupFSPath is where the cgroup filesystem is mounted in containers.
+const cgroupFSPath = "/sys/fs/cgroup"
+
+// isCgroupFSWritable returns true if the container's cgroup filesystem is
+// mounted read-write, as system container runtimes such as Sysbox and LXC do
+// to let the container manage nested cgroups. Docker and Kubernetes mount it
+// read-only. A host always mounts it read-write, so outside a container, as
+// reported by environment, false is returned.
+func isCgroupFSWritable(environment string) bool {
+	if environment != environmentContainer {
+		return false
+	}
+
+	entries, err := readMountInfo()
+	if err != nil {
+		return false
+	}
+
+	return cgroupFSWritableFromMounts(entries)
+}
+
+// cgroupFSWritableFromMounts returns true if the mount at cgroupFSPath in
+// entries is a cgroup filesystem mounted read-write. Under cgroup v1 the
+// controllers are mounted beneath a tmpfs, which is judged instead.
+func cgroupFSWritableFromMounts(entries []mountEntry) bool {
+	m, found := findMount(entries, cgroupFSPath)
+	if !found || m.Mountpoint != cgroupFSPath {
+		return false
+	}
+
+	switch m.FSType {
+	case "cgroup2", "cgroup", "tmpfs":
+		return !hasMountOption(m.Options, "ro")
+	}
+
+	return false
+}
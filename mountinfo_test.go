@@ -0,0 +1,266 @@
+package criprof
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMountInfo = `1197 1103 0:113 / / rw,relatime master:466 - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/l/A:/var/lib/docker/overlay2/l/B,upperdir=/var/lib/docker/overlay2/abc/diff,workdir=/var/lib/docker/overlay2/abc/work
+1198 1197 0:116 / /proc rw,nosuid,nodev,noexec,relatime - proc proc rw
+1201 1197 0:118 / /dev/shm rw,nosuid,nodev,noexec,relatime - tmpfs shm rw,size=65536k
+1210 1197 0:120 / /tmp rw,nosuid,nodev - tmpfs tmpfs rw,size=1048576k
+1211 1197 253:1 /srv/app\040data /data rw,relatime - ext4 /dev/vda1 rw
+1212 1197 253:1 /var/lib/kubelet/pods/0f1e/volumes/kubernetes.io~csi/pvc-1/mount /var/lib/app rw,relatime - ext4 /dev/vdb rw
+`
+
+func TestParseMountInfo(t *testing.T) {
+	entries, err := parseMountInfo(strings.NewReader(testMountInfo + "garbage line\n"))
+	if err != nil {
+		t.Fatalf("parseMountInfo() error = %v", err)
+	}
+
+	if len(entries) != 6 {
+		t.Fatalf("parseMountInfo() returned %d entries, want 6", len(entries))
+	}
+
+	if got := entries[4].Root; got != "/srv/app data" {
+		t.Errorf("entries[4].Root = %q, want %q", got, "/srv/app data")
+	}
+
+	if got := entries[2].SuperOptions; got != "rw,size=65536k" {
+		t.Errorf("entries[2].SuperOptions = %q, want %q", got, "rw,size=65536k")
+	}
+}
+
+func TestClassifyMounts(t *testing.T) {
+	entries, _ := parseMountInfo(strings.NewReader(testMountInfo))
+
+	mounts := classifyMounts(entries, []string{"/tmp", "/data", "/var/lib/app/cache", "/app"})
+
+	want := map[string]string{
+		"/tmp":               mountTmpfs,
+		"/data":              mountBind,
+		"/var/lib/app/cache": mountPVC,
+		"/app":               mountOverlay,
+	}
+
+	if len(mounts) != len(want) {
+		t.Fatalf("classifyMounts() returned %d mounts, want %d", len(mounts), len(want))
+	}
+
+	for _, m := range mounts {
+		if m.Type != want[m.Path] {
+			t.Errorf("mount for %s has type %q, want %q", m.Path, m.Type, want[m.Path])
+		}
+	}
+}
+
+func BenchmarkParseMountInfo(b *testing.B) {
+	b.ReportAllocs()
+
+	// Run parseMountInfo function b.N times.
+	for i := 0; i < b.N; i++ {
+		parseMountInfo(strings.NewReader(testMountInfo))
+	}
+}
+
+func TestShmSizeFromMounts(t *testing.T) {
+	tests := []struct {
+		name  string
+		mount string
+		want  int64
+	}{
+		{"docker default", "1201 1197 0:118 / /dev/shm rw,nosuid,nodev,noexec,relatime - tmpfs shm rw,size=65536k\n", 64 << 20},
+		{"shm-size 2g", "1201 1197 0:118 / /dev/shm rw,nosuid,nodev,noexec,relatime - tmpfs shm rw,size=2g\n", 2 << 30},
+		{"bytes", "1201 1197 0:118 / /dev/shm rw - tmpfs shm rw,size=1073741824\n", 1 << 30},
+		{"empty", "1201 1197 0:118 / /dev/shm rw - tmpfs shm rw,size=\n", 0},
+		{"percentage", "1201 1197 0:118 / /dev/shm rw - tmpfs shm rw,size=50%\n", 0},
+		{"host shm", "1201 1197 0:118 / /dev/shm rw - tmpfs tmpfs rw,inode64\n", 0},
+		{"absent", "1198 1197 0:116 / /proc rw - proc proc rw\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parseMountInfo(strings.NewReader(tt.mount))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := shmSizeFromMounts(entries); got != tt.want {
+				t.Errorf("shmSizeFromMounts() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetShmSize(t *testing.T) {
+	dir := withProcTree(t, testProcess{"1", "0", "app"})
+	writeTestFile(t, dir, "1/mountinfo", testMountInfo)
+
+	if got := getShmSize(); got != 64<<20 {
+		t.Errorf("getShmSize() = %d, want %d", got, 64<<20)
+	}
+}
+
+func TestReadMountInfoCached(t *testing.T) {
+	dir := withProcTree(t, testProcess{"1", "0", "app"})
+	writeTestFile(t, dir, "1/mountinfo", testMountInfo)
+
+	if got := getShmSize(); got != 64<<20 {
+		t.Fatalf("getShmSize() = %d, want %d", got, 64<<20)
+	}
+
+	writeTestFile(t, dir, "1/mountinfo", "1198 1197 0:116 / /proc rw - proc proc rw\n")
+
+	if got := getShmSize(); got != 64<<20 {
+		t.Errorf("getShmSize() = %d after mountinfo changed, want the cached %d", got, 64<<20)
+	}
+
+	resetMountInfoCache()
+
+	if got := getShmSize(); got != 0 {
+		t.Errorf("getShmSize() = %d after resetMountInfoCache, want 0", got)
+	}
+}
+
+func TestProcMaskedFromMounts(t *testing.T) {
+	const (
+		proc = "1198 1197 0:116 / /proc rw,nosuid,nodev,noexec,relatime - proc proc rw\n"
+		sys  = "1202 1197 0:119 / /sys rw,nosuid,nodev,noexec,relatime - sysfs sysfs rw\n"
+	)
+
+	tests := []struct {
+		name      string
+		mountinfo string
+		want      bool
+	}{
+		{"kcore masked", proc + "1105 1198 0:5 /null /proc/kcore rw,nosuid - devtmpfs udev rw,size=4041412k\n", true},
+		{"acpi masked", proc + "1108 1198 0:121 / /proc/acpi ro,relatime - tmpfs tmpfs ro\n", true},
+		{"proc sys read-only", proc + "1099 1198 0:116 /sys /proc/sys ro,nosuid,nodev,noexec,relatime - proc proc rw\n", true},
+		{"sys read-only", "1202 1197 0:119 / /sys ro,nosuid,nodev,noexec,relatime - sysfs sysfs ro\n", true},
+		{"privileged", proc + sys, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parseMountInfo(strings.NewReader(tt.mountinfo))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := procMaskedFromMounts(entries); got != tt.want {
+				t.Errorf("procMaskedFromMounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testDirInfo struct {
+	name string
+	mode os.FileMode
+}
+
+func (fi testDirInfo) Name() string       { return fi.name }
+func (fi testDirInfo) Size() int64        { return 4096 }
+func (fi testDirInfo) Mode() os.FileMode  { return os.ModeDir | fi.mode }
+func (fi testDirInfo) ModTime() time.Time { return time.Time{} }
+func (fi testDirInfo) IsDir() bool        { return true }
+func (fi testDirInfo) Sys() interface{}   { return nil }
+
+func TestScratchDirFrom(t *testing.T) {
+	entries, err := parseMountInfo(strings.NewReader(testMountInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readOnlyRoot, err := parseMountInfo(strings.NewReader(
+		"1197 1103 0:113 / / ro,relatime - overlay overlay rw\n",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		modes   map[string]os.FileMode
+		entries []mountEntry
+		euid    int
+		path    string
+		fsType  string
+	}{
+		{"working directory", map[string]os.FileMode{"/app": 0o777, "/tmp": 0o1777}, entries, 1000, "/app", "overlay"},
+		{"falls back to tmp", map[string]os.FileMode{"/app": 0o755, "/tmp": 0o1777}, entries, 1000, "/tmp", "tmpfs"},
+		{"root", map[string]os.FileMode{"/app": 0o755}, entries, 0, "/app", "overlay"},
+		{"read-only mount", map[string]os.FileMode{"/app": 0o777, "/tmp": 0o1777}, readOnlyRoot, 1000, "", ""},
+		{"nothing writable", map[string]os.FileMode{"/app": 0o555, "/tmp": 0o755}, entries, 1000, "", ""},
+		{"no mount table", map[string]os.FileMode{"/tmp": 0o1777}, nil, 1000, "/tmp", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stat := func(p string) (os.FileInfo, error) {
+				mode, ok := tt.modes[p]
+				if !ok {
+					return nil, os.ErrNotExist
+				}
+				return testDirInfo{name: p, mode: mode}, nil
+			}
+
+			path, fsType := scratchDirFrom([]string{"/app", "/tmp", "/var/tmp"}, stat, tt.entries, tt.euid)
+			if path != tt.path || fsType != tt.fsType {
+				t.Errorf("scratchDirFrom() = %q, %q, want %q, %q", path, fsType, tt.path, tt.fsType)
+			}
+		})
+	}
+}
+
+func TestCgroupFSWritableFromMounts(t *testing.T) {
+	const root = "1197 1103 0:113 / / rw,relatime - overlay overlay rw\n"
+
+	tests := []struct {
+		name      string
+		mountinfo string
+		want      bool
+	}{
+		{"docker v2", root + "1205 1197 0:30 / /sys/fs/cgroup ro,nosuid,nodev,noexec,relatime - cgroup2 cgroup rw\n", false},
+		{"sysbox v2", root + "1205 1197 0:30 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime - cgroup2 cgroup rw\n", true},
+		{"lxc v1", root + "1205 1197 0:31 / /sys/fs/cgroup rw,nosuid,nodev,noexec - tmpfs tmpfs rw,mode=755\n" +
+			"1206 1205 0:32 / /sys/fs/cgroup/memory rw,nosuid,nodev,noexec,relatime - cgroup cgroup rw,memory\n", true},
+		{"docker v1", root + "1205 1197 0:31 / /sys/fs/cgroup ro,nosuid,nodev,noexec - tmpfs tmpfs rw,mode=755\n" +
+			"1206 1205 0:32 / /sys/fs/cgroup/memory ro,nosuid,nodev,noexec,relatime - cgroup cgroup rw,memory\n", false},
+		{"not mounted", root, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parseMountInfo(strings.NewReader(tt.mountinfo))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := cgroupFSWritableFromMounts(entries); got != tt.want {
+				t.Errorf("cgroupFSWritableFromMounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCgroupFSWritable(t *testing.T) {
+	dir := withProcTree(t, testProcess{"1", "0", "systemd"})
+	writeTestFile(t, dir, "1/mountinfo",
+		"22 1 253:0 / / rw,relatime - ext4 /dev/vda1 rw\n"+
+			"27 22 0:23 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime - cgroup2 cgroup2 rw\n")
+
+	if !isCgroupFSWritable(environmentContainer) {
+		t.Error("isCgroupFSWritable(container) = false for a read-write cgroup2 mount")
+	}
+
+	// A host's own read-write cgroup mount is not a delegated one.
+	for _, env := range []string{environmentBareMetal, environmentVM} {
+		if isCgroupFSWritable(env) {
+			t.Errorf("isCgroupFSWritable(%q) = true outside a container", env)
+		}
+	}
+}
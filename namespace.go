@@ -0,0 +1,177 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Container network modes.
+const (
+	networkHost   = "host"   // Shares the host's network namespace
+	networkBridge = "bridge" // Own namespace with external connectivity
+	networkNone   = "none"   // Own namespace with loopback only
+)
+
+// hostInterfacePrefixes are interface name prefixes that only appear in a
+// host's network namespace: container bridges, veth peers and CNI devices.
+var hostInterfacePrefixes = []string{
+	"docker0",
+	"br-",
+	"cni",
+	"cbr0",
+	"virbr",
+	"veth",
+	"cali",
+	"flannel",
+	"cilium_",
+	"kube-ipvs",
+	"tunl",
+	"weave",
+}
+
+// sameNamespace returns true if the namespace links a and b, such as
+// /proc/self/ns/net and /proc/1/ns/net, refer to the same namespace.
+func sameNamespace(a, b string) (bool, error) {
+	la, err := os.Readlink(a)
+	if err != nil {
+		return false, err
+	}
+
+	lb, err := os.Readlink(b)
+	if err != nil {
+		return false, err
+	}
+
+	return la == lb, nil
+}
+
+// interfaceNames returns the names of the network interfaces visible to the
+// process. It is a variable so tests can substitute an interface list.
+var interfaceNames = func() ([]string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+
+	return names, nil
+}
+
+// getInterfaces returns the names of the network interfaces in the process's
+// network namespace, or nil if they cannot be listed.
+func getInterfaces() []string {
+	names, err := interfaceNames()
+	if err != nil {
+		return nil
+	}
+
+	return names
+}
+
+// getNetworkMode returns whether the container uses the host network, a
+// bridged network or no network. Outside a container, as reported by
+// environment, there is no container network to classify and "" is returned.
+func getNetworkMode(environment string) string {
+	if environment != environmentContainer {
+		return ""
+	}
+
+	names, err := interfaceNames()
+	if err != nil {
+		return ""
+	}
+
+	// A network namespace differing from PID 1's cannot be the host's when
+	// PID 1 is the host init, and otherwise carries no signal.
+	same, err := sameNamespace(
+		filepath.Join(procPath, "self", "ns", "net"),
+		filepath.Join(procPath, "1", "ns", "net"),
+	)
+	shared := err != nil || same
+
+	return classifyNetworkMode(names, shared)
+}
+
+// classifyNetworkMode classifies a network namespace by its interface names.
+// sharedWithInit reports whether the namespace is PID 1's (or unknown).
+func classifyNetworkMode(names []string, sharedWithInit bool) string {
+	external := false
+	for _, n := range names {
+		if n == "lo" {
+			continue
+		}
+		external = true
+
+		if !sharedWithInit {
+			continue
+		}
+
+		for _, p := range hostInterfacePrefixes {
+			if strings.HasPrefix(n, p) {
+				return networkHost
+			}
+		}
+	}
+
+	if !external {
+		return networkNone
+	}
+
+	return networkBridge
+}
+
+// isHostPID returns true if the process shares the host's PID namespace, as
+// with hostPID: true, making /proc/1 the host's init rather than the
+// container's. PID 1 is then in another mount namespace and runs an init
+// system; a pause process there indicates a shared pod process namespace
+// instead.
+func isHostPID() bool {
+	same, err := sameNamespace(
+		filepath.Join(procPath, "self", "ns", "mnt"),
+		filepath.Join(procPath, "1", "ns", "mnt"),
+	)
+	if err != nil || same {
+		return false
+	}
+
+	switch processComm("1") {
+	case "systemd", "init":
+		return true
+	}
+
+	return false
+}
+
+// isHostNamespace returns true if the process shares the host's namespace of
+// the given kind, such as "ipc", "net" or "uts", as with hostIPC or
+// hostNetwork. PID 1 is only the host's init under hostPID, so without it the
+// comparison carries no signal and false is returned.
+func isHostNamespace(kind string) bool {
+	if !isHostPID() {
+		return false
+	}
+
+	same, err := sameNamespace(
+		filepath.Join(procPath, "self", "ns", kind),
+		filepath.Join(procPath, "1", "ns", kind),
+	)
+
+	return err == nil && same
+}
+
+// isHostNetwork returns true if the process shares the host's network
+// namespace, as with hostNetwork or --network=host. Either the interface
+// evidence behind networkMode or, under hostPID, a namespace shared with the
+// host's init is sufficient.
+func isHostNetwork(networkMode string) bool {
+	return networkMode == networkHost || isHostNamespace("net")
+}
@@ -0,0 +1,220 @@
+package criprof
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTestNamespace creates a symlink at name beneath dir pointing at target,
+// mimicking a /proc/<pid>/ns entry.
+func writeTestNamespace(t *testing.T, dir, name, target string) string {
+	t.Helper()
+
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(target, p); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestSameNamespace(t *testing.T) {
+	dir := t.TempDir()
+	self := writeTestNamespace(t, dir, "self/ns/net", "net:[4026532281]")
+	same := writeTestNamespace(t, dir, "1/ns/net", "net:[4026532281]")
+	other := writeTestNamespace(t, dir, "2/ns/net", "net:[4026531840]")
+
+	if ok, err := sameNamespace(self, same); err != nil || !ok {
+		t.Errorf("sameNamespace(matching) = %v, %v, want true", ok, err)
+	}
+
+	if ok, err := sameNamespace(self, other); err != nil || ok {
+		t.Errorf("sameNamespace(differing) = %v, %v, want false", ok, err)
+	}
+
+	if _, err := sameNamespace(self, filepath.Join(dir, "missing")); err == nil {
+		t.Error("sameNamespace(missing) returned no error")
+	}
+}
+
+func TestClassifyNetworkMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		ifaces []string
+		shared bool
+		want   string
+	}{
+		{"host", []string{"lo", "eth0", "docker0", "veth1a2b3c"}, true, networkHost},
+		{"bridge", []string{"lo", "eth0"}, true, networkBridge},
+		{"none", []string{"lo"}, true, networkNone},
+		{"own namespace", []string{"lo", "eth0", "cni0"}, false, networkBridge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyNetworkMode(tt.ifaces, tt.shared); got != tt.want {
+				t.Errorf("classifyNetworkMode(%q) = %q, want %q", tt.ifaces, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHostPID(t *testing.T) {
+	tests := []struct {
+		name    string
+		init    string
+		initMnt string
+		want    bool
+	}{
+		{"hostPID", "systemd", "mnt:[4026531841]", true},
+		{"container init", "app", "mnt:[4026531841]", false},
+		{"shared pod namespace", "pause", "mnt:[4026531841]", false},
+		{"same mount namespace", "systemd", "mnt:[4026532282]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t,
+				testProcess{"4211", "0", "app"},
+				testProcess{"1", "0", tt.init},
+			)
+			writeTestNamespace(t, dir, "4211/ns/mnt", "mnt:[4026532282]")
+			writeTestNamespace(t, dir, "1/ns/mnt", tt.initMnt)
+
+			if got := isHostPID(); got != tt.want {
+				t.Errorf("isHostPID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHostPIDUnreadable(t *testing.T) {
+	withProcTree(t, testProcess{"1", "0", "systemd"})
+
+	if isHostPID() {
+		t.Error("isHostPID() = true without namespace entries")
+	}
+}
+
+// withInterfaces substitutes the interface list returned by interfaceNames for
+// the duration of the test.
+func withInterfaces(t *testing.T, names ...string) {
+	t.Helper()
+
+	old := interfaceNames
+	interfaceNames = func() ([]string, error) { return names, nil }
+	t.Cleanup(func() { interfaceNames = old })
+}
+
+func TestGetInterfaces(t *testing.T) {
+	withInterfaces(t, "lo", "eth0")
+
+	if got := getInterfaces(); !reflect.DeepEqual(got, []string{"lo", "eth0"}) {
+		t.Errorf("getInterfaces() = %v, want [lo eth0]", got)
+	}
+}
+
+func TestGetNetworkMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		ifaces []string
+		want   string
+	}{
+		{"bridged", []string{"lo", "eth0"}, networkBridge},
+		{"host", []string{"lo", "ens5", "docker0", "veth9f2c1a7"}, networkHost},
+		{"none", []string{"lo"}, networkNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withProcTree(t, testProcess{"1", "0", "app"})
+			withInterfaces(t, tt.ifaces...)
+
+			if got := getNetworkMode(environmentContainer); got != tt.want {
+				t.Errorf("getNetworkMode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNetworkModeNotContainer(t *testing.T) {
+	// A host's own eth0 in PID 1's namespace is not a bridged container.
+	withProcTree(t, testProcess{"1", "0", "systemd"})
+	withInterfaces(t, "lo", "eth0")
+
+	for _, env := range []string{environmentBareMetal, environmentVM} {
+		if got := getNetworkMode(env); got != "" {
+			t.Errorf("getNetworkMode(%q) = %q, want \"\"", env, got)
+		}
+	}
+}
+
+func TestIsHostNamespace(t *testing.T) {
+	tests := []struct {
+		name    string
+		initMnt string
+		shared  map[string]bool
+	}{
+		{"hostPID with hostIPC", "mnt:[4026531841]", map[string]bool{"ipc": true, "net": false, "uts": false}},
+		{"hostPID with hostNetwork", "mnt:[4026531841]", map[string]bool{"ipc": false, "net": true, "uts": true}},
+		{"hostPID only", "mnt:[4026531841]", map[string]bool{"ipc": false, "net": false, "uts": false}},
+		{"own PID namespace", "mnt:[4026532282]", map[string]bool{"ipc": true, "net": true, "uts": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t,
+				testProcess{"4211", "0", "app"},
+				testProcess{"1", "0", "systemd"},
+			)
+			writeTestNamespace(t, dir, "4211/ns/mnt", "mnt:[4026532282]")
+			writeTestNamespace(t, dir, "1/ns/mnt", tt.initMnt)
+
+			for kind, shared := range tt.shared {
+				writeTestNamespace(t, dir, "1/ns/"+kind, kind+":[4026531839]")
+
+				self := kind + ":[4026532283]"
+				if shared {
+					self = kind + ":[4026531839]"
+				}
+				writeTestNamespace(t, dir, "4211/ns/"+kind, self)
+			}
+
+			// Without hostPID, PID 1 is the container's own init.
+			hostPID := tt.initMnt != "mnt:[4026532282]"
+
+			for kind, shared := range tt.shared {
+				if got, want := isHostNamespace(kind), shared && hostPID; got != want {
+					t.Errorf("isHostNamespace(%q) = %v, want %v", kind, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsHostNetwork(t *testing.T) {
+	// Without hostPID, PID 1 carries no namespace signal.
+	withProcTree(t, testProcess{"1", "0", "app"})
+
+	tests := []struct {
+		mode string
+		want bool
+	}{
+		{networkHost, true},
+		{networkBridge, false},
+		{networkNone, false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isHostNetwork(tt.mode); got != tt.want {
+			t.Errorf("isHostNetwork(%q) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// procPaths are the /proc files detection relies on. Any that cannot be read
+// are reported in Inventory.DetectionNotes so an undetermined result can be
+// told apart from a genuine absence of signals. The cgroup files are covered
+// by cgroupNotes.
+var procPaths = []string{
+	"/proc/self/mountinfo",
+	"/proc/1/environ",
+	"/proc/1/cmdline",
+}
+
+// getDetectionNotes returns notes describing conditions that degraded
+// detection, such as /proc not being mounted.
+func getDetectionNotes(c *config) []string {
+	if _, err := os.Stat("/proc"); err != nil {
+		return []string{fmt.Sprintf("/proc unavailable: %s", describeError(err))}
+	}
+
+	return append(cgroupNotes(c.cgroupPaths), unreadableNotes(procPaths)...)
+}
+
+// cgroupNotes returns a note for each cgroup file that cannot be read or is
+// empty. An empty cgroup file exists but carries no signal, which differs from
+// the file being absent, as when cgroups are not mounted.
+func cgroupNotes(paths []string) []string {
+	var notes []string
+
+	for _, p := range paths {
+		cgroup, err := ioutil.ReadFile(p)
+		switch {
+		case err != nil:
+			notes = append(notes, fmt.Sprintf("%s unreadable: %s", p, describeError(err)))
+		case len(bytes.TrimSpace(cgroup)) == 0:
+			notes = append(notes, fmt.Sprintf("%s empty", p))
+		}
+	}
+
+	return notes
+}
+
+// unreadableNotes returns a note for each path that cannot be opened.
+func unreadableNotes(paths []string) []string {
+	var notes []string
+
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s unreadable: %s", p, describeError(err)))
+			continue
+		}
+		f.Close()
+	}
+
+	return notes
+}
+
+// describeError returns a short reason for a file access error.
+func describeError(err error) string {
+	switch {
+	case os.IsNotExist(err):
+		return "not found"
+	case os.IsPermission(err):
+		return "permission denied"
+	}
+
+	return err.Error()
+}
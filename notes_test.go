@@ -0,0 +1,57 @@
+package criprof
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnreadableNotes(t *testing.T) {
+	dir := t.TempDir()
+
+	readable := filepath.Join(dir, "cgroup")
+	if err := ioutil.WriteFile(readable, []byte("0::/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := filepath.Join(dir, "mountinfo")
+
+	notes := unreadableNotes([]string{readable, missing})
+	if len(notes) != 1 {
+		t.Fatalf("unreadableNotes() = %q, want a single note", notes)
+	}
+
+	if want := missing + " unreadable: not found"; notes[0] != want {
+		t.Errorf("unreadableNotes()[0] = %q, want %q", notes[0], want)
+	}
+}
+
+func TestDescribeError(t *testing.T) {
+	if got := describeError(os.ErrPermission); got != "permission denied" {
+		t.Errorf("describeError(ErrPermission) = %q, want %q", got, "permission denied")
+	}
+}
+
+func TestCgroupNotes(t *testing.T) {
+	dir := t.TempDir()
+	populated := writeTestFile(t, dir, "populated", "0::/kubepods.slice/cri-containerd-4f3a.scope\n")
+	empty := writeTestFile(t, dir, "empty", "")
+	absent := filepath.Join(dir, "absent")
+
+	notes := cgroupNotes([]string{populated, empty, absent})
+
+	want := []string{
+		empty + " empty",
+		absent + " unreadable: not found",
+	}
+	if len(notes) != len(want) {
+		t.Fatalf("cgroupNotes() = %q, want %q", notes, want)
+	}
+
+	for i := range want {
+		if notes[i] != want[i] {
+			t.Errorf("cgroupNotes()[%d] = %q, want %q", i, notes[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// ociConfigPaths are the locations checked for the OCI runtime bundle's
+// config.json. Runtimes do not normally expose the bundle inside the
+// container, so this is best-effort and usually finds nothing.
+var ociConfigPaths = []string{
+	"/config.json",
+	"/run/oci/config.json",
+	"/.oci/config.json",
+}
+
+// getOCISpecVersion returns the ociVersion of the container's OCI runtime
+// spec if a config.json is readable, or "" otherwise.
+func getOCISpecVersion() string {
+	for _, p := range ociConfigPaths {
+		f, err := os.Open(p)
+		if err != nil {
+			continue
+		}
+
+		v, err := parseOCIVersion(f)
+		f.Close()
+
+		if err == nil && v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// parseOCIVersion returns the ociVersion field of an OCI runtime config.json.
+func parseOCIVersion(r io.Reader) (string, error) {
+	var spec struct {
+		OCIVersion string `json:"ociVersion"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&spec); err != nil {
+		return "", err
+	}
+
+	return spec.OCIVersion, nil
+}
@@ -0,0 +1,45 @@
+package criprof
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOCIConfig = `{
+	"ociVersion": "1.0.2-dev",
+	"process": {"terminal": false, "args": ["sh"]},
+	"root": {"path": "rootfs", "readonly": true}
+}`
+
+func TestParseOCIVersion(t *testing.T) {
+	v, err := parseOCIVersion(strings.NewReader(testOCIConfig))
+	if err != nil {
+		t.Fatalf("parseOCIVersion() error = %v", err)
+	}
+
+	if v != "1.0.2-dev" {
+		t.Errorf("parseOCIVersion() = %q, want %q", v, "1.0.2-dev")
+	}
+
+	if _, err := parseOCIVersion(strings.NewReader("not json")); err == nil {
+		t.Error("parseOCIVersion() accepted invalid JSON")
+	}
+}
+
+func TestGetOCISpecVersion(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(path, []byte(testOCIConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := ociConfigPaths
+	ociConfigPaths = []string{filepath.Join(dir, "missing.json"), path}
+	t.Cleanup(func() { ociConfigPaths = old })
+
+	if got := getOCISpecVersion(); got != "1.0.2-dev" {
+		t.Errorf("getOCISpecVersion() = %q, want %q", got, "1.0.2-dev")
+	}
+}
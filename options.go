@@ -0,0 +1,235 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// defaultTimeout bounds each network probe made during detection.
+const defaultTimeout = 2 * time.Second
+
+// config holds the detection settings applied by Options.
+type config struct {
+	timeout time.Duration
+	network bool
+	retries int
+	backoff time.Duration
+	mmds    bool
+	egress  string
+
+	cgroupPaths []string
+	overrides   bool
+
+	// deadline bounds all network probes when WithMaxDuration is set or ctx
+	// has a deadline, and truncated records that a probe was skipped or cut
+	// short by it or by ctx being done.
+	ctx         context.Context
+	maxDuration time.Duration
+	deadline    time.Time
+	truncated   bool
+	partial     bool
+	profile     bool
+}
+
+// Option configures detection performed by NewWithOptions.
+type Option func(*config)
+
+// newConfig returns the default configuration with opts applied.
+func newConfig(opts ...Option) *config {
+	c := &config{
+		ctx:         context.Background(),
+		timeout:     defaultTimeout,
+		network:     true,
+		cgroupPaths: cgroupPaths,
+		overrides:   AllowOverrides,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.maxDuration > 0 {
+		c.deadline = time.Now().Add(c.maxDuration)
+	}
+
+	if d, ok := c.ctx.Deadline(); ok && (c.deadline.IsZero() || d.Before(c.deadline)) {
+		c.deadline = d
+	}
+
+	return c
+}
+
+// WithTimeout bounds each network probe, such as the Kubernetes API and
+// Docker Swarm port checks, to d.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithoutNetwork disables detection that performs network I/O, such as the
+// Kubernetes API and Docker Swarm port probes, leaving only file and
+// environment checks.
+func WithoutNetwork() Option {
+	return func(c *config) {
+		c.network = false
+	}
+}
+
+// WithRetry retries a failed network probe up to retries more times, waiting
+// backoff before the first retry and doubling the wait after each one. Retries
+// stop once the next attempt would start beyond the probe's timeout, so a
+// transient failure such as a refused connection is retried without extending
+// detection past its network budget.
+func WithRetry(retries int, backoff time.Duration) Option {
+	return func(c *config) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithMMDS queries the Firecracker microVM metadata service when running in
+// a Firecracker guest. It is off by default because MMDS holds data supplied
+// by the platform rather than the runtime.
+func WithMMDS() Option {
+	return func(c *config) {
+		c.mmds = true
+	}
+}
+
+// WithEgressProbe checks for internet egress by dialing addr, a host:port
+// such as "1.1.1.1:53", over TCP. An empty addr uses defaultEgressAddr. It is
+// off by default because it sends traffic off the host.
+func WithEgressProbe(addr string) Option {
+	return func(c *config) {
+		if addr == "" {
+			addr = defaultEgressAddr
+		}
+		c.egress = addr
+	}
+}
+
+// WithCgroupPaths replaces the cgroup membership files inspected, in order,
+// such as a host's /proc mounted at another path. Under hostPID only the first
+// path is read.
+func WithCgroupPaths(paths ...string) Option {
+	return func(c *config) {
+		c.cgroupPaths = paths
+	}
+}
+
+// WithOverrides enables the CRIPROF_FORCE_RUNTIME, CRIPROF_FORCE_SCHEDULER and
+// CRIPROF_FORCE_IMAGE_FORMAT environment variables, which short-circuit
+// detection to a fixed value. It is intended for reproducing bug reports and
+// for tests; without it production detection is never affected by a stray
+// environment variable.
+func WithOverrides() Option {
+	return func(c *config) {
+		c.overrides = true
+	}
+}
+
+// WithMaxDuration caps the total time detection spends on network probes at
+// d. Probes are cut short or skipped once d has elapsed, and the values they
+// would have determined are reported as undetermined with ReasonSkipped, so a
+// partial Inventory is returned rather than an error.
+func WithMaxDuration(d time.Duration) Option {
+	return func(c *config) {
+		c.maxDuration = d
+	}
+}
+
+// WithPartialResults makes NewWithContext return the Inventory gathered before
+// its context was done, instead of the context's error.
+func WithPartialResults() Option {
+	return func(c *config) {
+		c.partial = true
+	}
+}
+
+// withProfile enables the enrichment performed by Profile.
+func withProfile() Option {
+	return func(c *config) {
+		c.profile = true
+	}
+}
+
+// withContext bounds network probes by ctx, for NewWithContext.
+func withContext(ctx context.Context) Option {
+	return func(c *config) {
+		c.ctx = ctx
+	}
+}
+
+// probeTimeout returns the time a probe may take: the configured timeout, or
+// less if the WithMaxDuration or context deadline is nearer.
+func (c *config) probeTimeout() time.Duration {
+	if c.deadline.IsZero() {
+		return c.timeout
+	}
+
+	if left := time.Until(c.deadline); left < c.timeout {
+		return left
+	}
+
+	return c.timeout
+}
+
+// probe runs fn, retrying it according to the configured retry policy until it
+// succeeds, and reports whether it did. fn is not run once the deadline has
+// passed or the context is done.
+func (c *config) probe(fn func() bool) bool {
+	budget := c.probeTimeout()
+	if budget <= 0 || c.ctx.Err() != nil {
+		c.truncated = true
+		return false
+	}
+
+	deadline := time.Now().Add(budget)
+	wait := c.backoff
+
+	for attempt := 0; ; attempt++ {
+		if fn() {
+			return true
+		}
+
+		if (!c.deadline.IsZero() && time.Now().After(c.deadline)) || c.ctx.Err() != nil {
+			c.truncated = true
+		}
+
+		if attempt >= c.retries || time.Now().Add(wait).After(deadline) {
+			return false
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-c.ctx.Done():
+			c.truncated = true
+			return false
+		}
+		wait *= 2
+	}
+}
+
+// get issues a GET request for url with client, abandoned once the context is
+// done.
+func (c *config) get(client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Do(req)
+}
+
+// dial opens a TCP connection to addr within the probe timeout, abandoned once
+// the context is done.
+func (c *config) dial(addr string) (net.Conn, error) {
+	d := net.Dialer{Timeout: c.probeTimeout()}
+	return d.DialContext(c.ctx, "tcp", addr)
+}
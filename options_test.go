@@ -0,0 +1,248 @@
+package criprof
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewConfig(t *testing.T) {
+	if got := newConfig().timeout; got != defaultTimeout {
+		t.Errorf("newConfig().timeout = %v, want %v", got, defaultTimeout)
+	}
+
+	if got := newConfig(WithTimeout(50 * time.Millisecond)).timeout; got != 50*time.Millisecond {
+		t.Errorf("WithTimeout(50ms) timeout = %v, want 50ms", got)
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	withOverrides(t)
+	withEnvironment(t, map[string]string{
+		overrideRuntime:   "podman",
+		overrideScheduler: "nomad",
+	})
+
+	i := NewWithOptions(WithTimeout(time.Millisecond))
+
+	if i.Runtime != "podman" || i.Scheduler != "nomad" {
+		t.Errorf("NewWithOptions() = %s/%s, want podman/nomad", i.Runtime, i.Scheduler)
+	}
+}
+
+func TestWithoutNetwork(t *testing.T) {
+	if !newConfig().network {
+		t.Error("newConfig().network = false, want network enabled by default")
+	}
+
+	c := newConfig(WithoutNetwork())
+	if c.network {
+		t.Fatal("WithoutNetwork() left network enabled")
+	}
+
+	withEnvironment(t, nil)
+
+	if isSwarm(c) {
+		t.Error("isSwarm() = true with network disabled")
+	}
+
+	if isKubernetes(c) {
+		t.Error("isKubernetes() = true with network disabled")
+	}
+}
+
+func TestProbeRetry(t *testing.T) {
+	c := newConfig(WithTimeout(time.Second), WithRetry(2, time.Millisecond))
+
+	calls := 0
+	ok := c.probe(func() bool {
+		calls++
+		return calls > 1
+	})
+
+	if !ok || calls != 2 {
+		t.Errorf("probe() = %v after %d calls, want success on the retry", ok, calls)
+	}
+
+	calls = 0
+	if c.probe(func() bool { calls++; return false }) || calls != 3 {
+		t.Errorf("probe() made %d calls before giving up, want 3", calls)
+	}
+}
+
+func TestProbeRetryBudget(t *testing.T) {
+	c := newConfig(WithTimeout(10*time.Millisecond), WithRetry(5, time.Second))
+
+	calls := 0
+	if c.probe(func() bool { calls++; return false }) || calls != 1 {
+		t.Errorf("probe() made %d calls, want no retry beyond the timeout", calls)
+	}
+}
+
+func TestProbeWithoutRetry(t *testing.T) {
+	calls := 0
+	if newConfig().probe(func() bool { calls++; return false }) || calls != 1 {
+		t.Errorf("probe() made %d calls, want 1 without a retry policy", calls)
+	}
+}
+
+func TestWithMaxDuration(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := newConfig(WithTimeout(time.Second), WithMaxDuration(100*time.Millisecond))
+
+	slow := func() bool {
+		client := &http.Client{Timeout: c.probeTimeout()}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return true
+	}
+
+	start := time.Now()
+
+	var ran int
+	for i := 0; i < 3; i++ {
+		if c.probe(func() bool { ran++; return slow() }) {
+			t.Errorf("probe %d succeeded past the cap", i)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > 180*time.Millisecond {
+		t.Errorf("probes took %v, want them capped near 100ms", elapsed)
+	}
+
+	if ran != 1 {
+		t.Errorf("ran %d probes, want 1 before the cap", ran)
+	}
+
+	if !c.truncated {
+		t.Error("truncated = false after probes were capped")
+	}
+}
+
+func TestWithMaxDurationUnset(t *testing.T) {
+	c := newConfig(WithTimeout(50 * time.Millisecond))
+
+	if got := c.probeTimeout(); got != 50*time.Millisecond {
+		t.Errorf("probeTimeout() = %v, want 50ms", got)
+	}
+
+	c.probe(func() bool { return false })
+	if c.truncated {
+		t.Error("truncated = true without WithMaxDuration")
+	}
+}
+
+func TestNewWithContextPartial(t *testing.T) {
+	// Only Profile queries metadata services. The Azure metadata service
+	// answers at once; the ECS agent, probed later, does not answer before the
+	// context's deadline.
+	withAzureIMDS(t, `{"resourceGroupName":"prod","subscriptionId":"8d2f6c1a-3b4e-4f5a-9c7d-0e1f2a3b4c5d","tagsList":[]}`)
+	withEnvironment(t, map[string]string{"AWS_EXECUTION_ENV": "AWS_ECS_EC2"})
+	withCgroupFiles(t, "0::/system.slice/docker-"+testECSDockerID+".scope\n")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	t.Cleanup(srv.Close)
+
+	old := ecsIntrospectionURL
+	ecsIntrospectionURL = srv.URL
+	t.Cleanup(func() { ecsIntrospectionURL = old })
+
+	t.Run("strict", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		i, err := NewWithContext(ctx, WithTimeout(2*time.Second), withProfile())
+		if !errors.Is(err, context.DeadlineExceeded) || i != nil {
+			t.Errorf("NewWithContext() = %v, %v, want nil, %v", i, err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		i := Profile(ctx, WithTimeout(2*time.Second))
+
+		if i.CloudSubscriptionID != "8d2f6c1a-3b4e-4f5a-9c7d-0e1f2a3b4c5d" {
+			t.Errorf("CloudSubscriptionID = %q, want the subscription from the probe before the deadline", i.CloudSubscriptionID)
+		}
+
+		if !i.Partial {
+			t.Error("Partial = false after the deadline")
+		}
+
+		if got := i.DetectionNotes[len(i.DetectionNotes)-1]; got != "detection truncated: "+context.DeadlineExceeded.Error() {
+			t.Errorf("last detection note = %q, want the truncation", got)
+		}
+	})
+
+	t.Run("lean", func(t *testing.T) {
+		i, err := NewWithContext(context.Background(), WithTimeout(2*time.Second))
+		if err != nil {
+			t.Fatalf("NewWithContext() error = %v without metadata probes", err)
+		}
+
+		if i.CloudProvider != cloudAzure || i.CloudSubscriptionID != "" {
+			t.Errorf("NewWithContext() = provider %q, subscription %q, want azure from DMI alone", i.CloudProvider, i.CloudSubscriptionID)
+		}
+	})
+}
+
+func TestNewWithContextComplete(t *testing.T) {
+	withOverrides(t)
+	withEnvironment(t, map[string]string{overrideScheduler: "nomad"})
+
+	i, err := NewWithContext(context.Background(), WithoutNetwork())
+	if err != nil {
+		t.Fatalf("NewWithContext() error = %v", err)
+	}
+
+	if i.Partial || i.Scheduler != "nomad" {
+		t.Errorf("NewWithContext() = partial %v, scheduler %q, want complete nomad", i.Partial, i.Scheduler)
+	}
+}
+
+func TestProbeContextCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := newConfig(withContext(ctx), WithTimeout(10*time.Second), WithRetry(3, time.Second))
+	client := &http.Client{Timeout: c.probeTimeout()}
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	ok := c.probe(func() bool {
+		resp, err := c.get(client, srv.URL)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+
+		return true
+	})
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("probe() returned %v after the context was cancelled, want promptly", elapsed)
+	}
+
+	if ok || !c.truncated {
+		t.Errorf("probe() = %v, truncated %v, want false, true", ok, c.truncated)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+// AllowOverrides enables the CRIPROF_FORCE_* environment variables for every
+// detection pass, as if WithOverrides were given.
+//
+// Deprecated: use WithOverrides, which does not affect concurrent callers.
+var AllowOverrides bool
+
+// Environment variables that force a detection result when overrides are
+// enabled.
+const (
+	overrideRuntime     = "CRIPROF_FORCE_RUNTIME"      // Forces Inventory.Runtime
+	overrideScheduler   = "CRIPROF_FORCE_SCHEDULER"    // Forces Inventory.Scheduler
+	overrideImageFormat = "CRIPROF_FORCE_IMAGE_FORMAT" // Forces Inventory.ImageFormat
+)
+
+// override returns the value of the override environment variable key if c
+// allows overrides and the variable is set to a non-empty value.
+func override(c *config, key string) (string, bool) {
+	if !c.overrides {
+		return "", false
+	}
+
+	v, ok := EnvironmentVariables[key]
+	if !ok || v == "" {
+		return "", false
+	}
+
+	return v, true
+}
@@ -0,0 +1,63 @@
+package criprof
+
+import "testing"
+
+// withOverrides enables the deprecated AllowOverrides default for the
+// duration of the test.
+func withOverrides(t *testing.T) {
+	t.Helper()
+
+	AllowOverrides = true
+	t.Cleanup(func() { AllowOverrides = false })
+}
+
+func TestOverride(t *testing.T) {
+	withEnvironment(t, map[string]string{
+		overrideRuntime:     "podman",
+		overrideScheduler:   "nomad",
+		overrideImageFormat: "oci",
+	})
+
+	c := newConfig(WithOverrides())
+
+	if got := getRuntime(c); got != "podman" {
+		t.Errorf("getRuntime() = %q, want %q", got, "podman")
+	}
+
+	if got := getScheduler(c); got != "nomad" {
+		t.Errorf("getScheduler() = %q, want %q", got, "nomad")
+	}
+
+	if got, _ := getImageFormat(c); got != "oci" {
+		t.Errorf("getImageFormat() = %q, want %q", got, "oci")
+	}
+
+	if got := NewWithOptions(WithoutNetwork(), WithOverrides()).Runtime; got != "podman" {
+		t.Errorf("NewWithOptions(WithOverrides()).Runtime = %q, want %q", got, "podman")
+	}
+}
+
+func TestOverrideDisabled(t *testing.T) {
+	withEnvironment(t, map[string]string{overrideRuntime: "podman"})
+
+	if _, ok := override(newConfig(), overrideRuntime); ok {
+		t.Error("override() applied without WithOverrides")
+	}
+}
+
+func TestOverrideAllowOverrides(t *testing.T) {
+	withOverrides(t)
+	withEnvironment(t, map[string]string{overrideRuntime: "podman"})
+
+	if v, ok := override(newConfig(), overrideRuntime); !ok || v != "podman" {
+		t.Errorf("override() = %q, %v, want AllowOverrides to enable overrides by default", v, ok)
+	}
+}
+
+func TestOverrideEmpty(t *testing.T) {
+	withEnvironment(t, map[string]string{overrideRuntime: ""})
+
+	if _, ok := override(newConfig(WithOverrides()), overrideRuntime); ok {
+		t.Error("override() applied for an empty value")
+	}
+}
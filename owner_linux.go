@@ -0,0 +1,22 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+//go:build linux
+// +build linux
+
+package criprof
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the UID owning the file described by fi.
+func fileOwner(fi os.FileInfo) (int, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return int(st.Uid), true
+}
@@ -0,0 +1,14 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package criprof
+
+import "os"
+
+// fileOwner is unsupported outside Linux, where cgroups do not exist.
+func fileOwner(fi os.FileInfo) (int, bool) {
+	return 0, false
+}
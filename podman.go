@@ -0,0 +1,51 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"os"
+	"strings"
+)
+
+// containerEnvPath is the file Podman and CRI-O create in every container.
+var containerEnvPath = "/run/.containerenv"
+
+// isPodmanConmon returns true if conmon is an ancestor of the process outside
+// of Kubernetes, identifying Podman rather than CRI-O.
+func isPodmanConmon() bool {
+	if _, ok := EnvironmentVariables["KUBERNETES_SERVICE_HOST"]; ok {
+		return false
+	}
+
+	return hasAncestor("conmon")
+}
+
+// isPodmanMachine returns true if the workload runs inside a Podman machine
+// VM, as used by Podman on macOS and Windows, rather than on native Linux.
+func isPodmanMachine(hostname string) bool {
+	// Check if the hostname matches the podman-machine-<name> convention.
+	if strings.HasPrefix(hostname, "podman-machine") {
+		return true
+	}
+
+	// Check if CONTAINER_HOST points at a Podman machine connection.
+	if v, ok := EnvironmentVariables["CONTAINER_HOST"]; ok && strings.Contains(v, "podman") {
+		return true
+	}
+
+	return false
+}
+
+// getPodmanPod returns the name of the Podman pod the container belongs to, or
+// "" if it is not in a pod.
+func getPodmanPod() string {
+	f, err := os.Open(containerEnvPath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	// .containerenv uses the same KEY="value" lines as os-release.
+	return parseOSRelease(f)["pod"]
+}
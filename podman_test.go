@@ -0,0 +1,89 @@
+package criprof
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsPodmanMachine(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostname string
+		env      map[string]string
+		want     bool
+	}{
+		{"hostname", "podman-machine-default", nil, true},
+		{"container host", "web-1", map[string]string{"CONTAINER_HOST": "ssh://core@127.0.0.1:50123/run/user/501/podman/podman.sock"}, true},
+		{"docker host", "web-1", map[string]string{"CONTAINER_HOST": "unix:///var/run/docker.sock"}, false},
+		{"native", "web-1", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEnvironment(t, tt.env)
+
+			if got := isPodmanMachine(tt.hostname); got != tt.want {
+				t.Errorf("isPodmanMachine(%q) = %v, want %v", tt.hostname, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectRuntimesConmon(t *testing.T) {
+	dir := withProcTree(t,
+		testProcess{"2", "1", "app"},
+		testProcess{"1", "0", "conmon"},
+	)
+
+	old := containerEnvPath
+	containerEnvPath = writeTestFile(t, dir, "containerenv", "engine=\"podman-4.6.1\"\n")
+	t.Cleanup(func() { containerEnvPath = old })
+
+	// Keep the host's own Docker markers and cgroup out of the result.
+	oldInit, oldEnv := dockerInitPath, dockerEnvPath
+	dockerInitPath = filepath.Join(dir, "missing")
+	dockerEnvPath = filepath.Join(dir, "missing")
+	t.Cleanup(func() { dockerInitPath, dockerEnvPath = oldInit, oldEnv })
+
+	withCgroupFiles(t, "0::/\n")
+	withEnvironment(t, nil)
+
+	runtimes := detectRuntimes(newConfig())
+	if len(runtimes) == 0 || !containsString(runtimes, runtimePodman) {
+		t.Fatalf("detectRuntimes() = %q, want podman detected", runtimes)
+	}
+
+	if containsString(runtimes, runtimeContainerD) {
+		t.Errorf("detectRuntimes() = %q, want containerenv attributed to podman only", runtimes)
+	}
+
+	withEnvironment(t, map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1"})
+
+	if isPodmanConmon() {
+		t.Error("isPodmanConmon() = true under Kubernetes, where conmon indicates CRI-O")
+	}
+}
+
+func TestGetPodmanPod(t *testing.T) {
+	tests := []struct {
+		name         string
+		containerenv string
+		want         string
+	}{
+		{"pod", "engine=\"podman-4.6.1\"\nname=\"web\"\nid=\"4f3a9c2b1d0e\"\nimage=\"docker.io/library/nginx:latest\"\npod=\"frontend\"\nrootless=1\n", "frontend"},
+		{"standalone", "engine=\"podman-4.6.1\"\nname=\"web\"\nid=\"4f3a9c2b1d0e\"\nrootless=1\n", ""},
+		{"unprivileged", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := containerEnvPath
+			containerEnvPath = writeTestFile(t, t.TempDir(), ".containerenv", tt.containerenv)
+			t.Cleanup(func() { containerEnvPath = old })
+
+			if got := getPodmanPod(); got != tt.want {
+				t.Errorf("getPodmanPod() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
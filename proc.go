@@ -0,0 +1,311 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procPath is the mount point of the proc filesystem.
+var procPath = "/proc"
+
+// maxAncestors bounds walks up the process tree.
+const maxAncestors = 32
+
+// clockTicks is USER_HZ, the unit of process times in /proc. It is 100 on all
+// Linux architectures Go supports.
+const clockTicks = 100
+
+// getInitCmdline returns the command line the container's PID 1 was started
+// with, or nil if /proc/1 is not readable. Unless full is set only the
+// executable is returned, since arguments routinely carry credentials that
+// should not reach logs or metric labels by default.
+func getInitCmdline(full bool) []string {
+	args := processCmdline("1")
+	if !full && len(args) > 1 {
+		return args[:1]
+	}
+
+	return args
+}
+
+// parseCmdline splits a NUL-separated /proc/<pid>/cmdline into arguments.
+func parseCmdline(cmdline []byte) []string {
+	cmdline = bytes.TrimRight(cmdline, "\x00")
+	if len(cmdline) == 0 {
+		return nil
+	}
+
+	var args []string
+	for _, arg := range bytes.Split(cmdline, []byte{0}) {
+		args = append(args, string(arg))
+	}
+
+	return args
+}
+
+// hasAncestor returns true if any ancestor of the current process has a
+// command name starting with prefix.
+func hasAncestor(prefix string) bool {
+	for _, comm := range processChain(maxAncestors) {
+		if strings.HasPrefix(comm, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// processChain returns the command names of the current process's ancestors,
+// nearest first, following PPid links until PID 1 or depth ancestors have been
+// visited. Ancestors outside the process's PID namespace, such as a runtime
+// shim on the host, are only visible when the host PID namespace is shared.
+func processChain(depth int) []string {
+	var chain []string
+	for _, pid := range ancestorPIDs(depth) {
+		chain = append(chain, processComm(pid))
+	}
+
+	return chain
+}
+
+// ancestorPIDs returns the PIDs of the current process's ancestors, nearest
+// first, as walked by processChain.
+func ancestorPIDs(depth int) []string {
+	var pids []string
+
+	pid := "self"
+	for i := 0; i < depth; i++ {
+		ppid, err := parentPID(pid)
+		if err != nil || ppid == "0" {
+			break
+		}
+
+		pids = append(pids, ppid)
+		pid = ppid
+	}
+
+	return pids
+}
+
+// ancestorCmdline returns the arguments of the nearest ancestor whose
+// executable name starts with prefix, or nil if there is none. Unlike
+// hasAncestor it is not limited by the 15 character command name, so it can
+// tell apart shims such as containerd-shim-runc-v2 and
+// containerd-shim-wasmtime-v1.
+func ancestorCmdline(prefix string) []string {
+	for _, pid := range ancestorPIDs(maxAncestors) {
+		args := processCmdline(pid)
+		if len(args) > 0 && strings.HasPrefix(filepath.Base(args[0]), prefix) {
+			return args
+		}
+	}
+
+	return nil
+}
+
+// processCmdline returns the arguments of pid from /proc/<pid>/cmdline.
+func processCmdline(pid string) []string {
+	cmdline, err := ioutil.ReadFile(filepath.Join(procPath, pid, "cmdline"))
+	if err != nil {
+		return nil
+	}
+
+	return parseCmdline(cmdline)
+}
+
+// parentPID returns the PPid of pid from /proc/<pid>/status.
+func parentPID(pid string) (string, error) {
+	return procStatusField(pid, "PPid")
+}
+
+// procStatusField returns the value of field from /proc/<pid>/status.
+func procStatusField(pid, field string) (string, error) {
+	f, err := os.Open(filepath.Join(procPath, pid, "status"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	prefix := field + ":"
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v := strings.TrimPrefix(scanner.Text(), prefix); v != scanner.Text() {
+			return strings.TrimSpace(v), nil
+		}
+	}
+
+	return "", os.ErrNotExist
+}
+
+// processComm returns the command name of pid from /proc/<pid>/comm, or "" if
+// it cannot be read.
+func processComm(pid string) string {
+	comm, err := ioutil.ReadFile(filepath.Join(procPath, pid, "comm"))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(comm))
+}
+
+// injectedInitPaths are where runtimes mount the init they inject as PID 1
+// with --init: Docker's tini and Podman's catatonit.
+var injectedInitPaths = []string{
+	"/sbin/docker-init",
+	"/dev/init",
+	"/run/podman-init",
+}
+
+// isRuntimeInitInjected returns true if PID 1 is an init the runtime injected
+// to reap zombies, rather than the application or an init shipped in the
+// image.
+func isRuntimeInitInjected() bool {
+	if isHostPID() {
+		return false
+	}
+
+	if processComm("1") == "docker-init" {
+		return true
+	}
+
+	args := processCmdline("1")
+	if len(args) == 0 {
+		return false
+	}
+
+	for _, p := range injectedInitPaths {
+		if args[0] == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// systemdMarkerPath is the directory systemd creates when it boots as the
+// init system.
+var systemdMarkerPath = "/run/systemd/system"
+
+// isSystemdInContainer returns true if a container runs systemd as its PID 1,
+// as system containers under LXC, LXD or Sysbox do. Under hostPID, PID 1 is
+// the host's systemd and is not counted.
+func isSystemdInContainer(runtime string) bool {
+	if runtime == runtimeUndetermined || isHostPID() {
+		return false
+	}
+
+	if processComm("1") != "systemd" {
+		return false
+	}
+
+	_, err := os.Stat(systemdMarkerPath)
+	return err == nil
+}
+
+// getContainerStartedAt returns when the container's PID 1 started, or nil if
+// it cannot be determined. Under hostPID, PID 1 is the host's init, whose start
+// time is the host's boot rather than the container's.
+func getContainerStartedAt() *time.Time {
+	if isHostPID() {
+		return nil
+	}
+
+	stat, err := ioutil.ReadFile(filepath.Join(procPath, "1", "stat"))
+	if err != nil {
+		return nil
+	}
+
+	ticks, ok := parseStartTime(string(stat))
+	if !ok {
+		return nil
+	}
+
+	f, err := os.Open(filepath.Join(procPath, "stat"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	btime, ok := parseBootTime(f)
+	if !ok {
+		return nil
+	}
+
+	started := time.Unix(btime, 0).Add(time.Duration(ticks) * time.Second / clockTicks)
+
+	return &started
+}
+
+// containerUptime returns how many whole seconds ago started was, or 0 if it
+// is unknown.
+func containerUptime(started *time.Time) int64 {
+	if started == nil {
+		return 0
+	}
+
+	return int64(time.Since(*started) / time.Second)
+}
+
+// parseStartTime returns the starttime field of a /proc/<pid>/stat line, in
+// clock ticks since boot. Fields are counted from the closing parenthesis of
+// the command name, which may itself contain spaces.
+func parseStartTime(stat string) (uint64, bool) {
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, false
+	}
+
+	// starttime is field 22; the first field after the command name is 3.
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 20 {
+		return 0, false
+	}
+
+	ticks, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return ticks, true
+}
+
+// parseBootTime returns the btime line of /proc/stat, the boot time in
+// seconds since the epoch.
+func parseBootTime(r io.Reader) (int64, bool) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "btime" {
+			btime, err := strconv.ParseInt(fields[1], 10, 64)
+			return btime, err == nil
+		}
+	}
+
+	return 0, false
+}
+
+// readNofileLimit reads the process's open file limits. It is a variable so
+// tests can stub the getrlimit call.
+var readNofileLimit = nofileLimit
+
+// getOpenFilesLimit returns the soft and hard limits on open file descriptors,
+// or 0, 0 if they cannot be read.
+func getOpenFilesLimit() (uint64, uint64) {
+	soft, hard, err := readNofileLimit()
+	if err != nil {
+		return 0, 0
+	}
+
+	return soft, hard
+}
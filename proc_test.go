@@ -0,0 +1,268 @@
+package criprof
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCmdline(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmdline string
+		want    []string
+	}{
+		{"args", "/usr/bin/python3\x00-m\x00http.server\x00", []string{"/usr/bin/python3", "-m", "http.server"}},
+		{"empty arg", "sh\x00-c\x00\x00echo\x00", []string{"sh", "-c", "", "echo"}},
+		{"no trailing nul", "/pause", []string{"/pause"}},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCmdline([]byte(tt.cmdline)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCmdline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// testProcess describes a process in a fake /proc tree.
+type testProcess struct {
+	pid  string
+	ppid string
+	comm string
+}
+
+// withProcTree points procPath at a temporary /proc tree containing procs for
+// the duration of the test. The first process is also linked as "self".
+func withProcTree(t *testing.T, procs ...testProcess) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for i, p := range procs {
+		status := "Name:\t" + p.comm + "\nState:\tS (sleeping)\nPid:\t" + p.pid + "\nPPid:\t" + p.ppid + "\n"
+		writeTestFile(t, dir, filepath.Join(p.pid, "status"), status)
+		writeTestFile(t, dir, filepath.Join(p.pid, "comm"), p.comm+"\n")
+
+		if i == 0 {
+			if err := os.Symlink(p.pid, filepath.Join(dir, "self")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	old := procPath
+	procPath = dir
+	t.Cleanup(func() { procPath = old })
+
+	return dir
+}
+
+func TestHasAncestor(t *testing.T) {
+	withProcTree(t,
+		testProcess{"4211", "4188", "app"},
+		testProcess{"4188", "4102", "sh"},
+		testProcess{"4102", "1", "containerd-shim-runc-v2"},
+		testProcess{"1", "0", "systemd"},
+	)
+
+	if !hasAncestor("containerd-shim") {
+		t.Error("hasAncestor(containerd-shim) = false with a shim ancestor")
+	}
+
+	if hasAncestor("conmon") {
+		t.Error("hasAncestor(conmon) = true without a conmon ancestor")
+	}
+}
+
+func TestProcessChain(t *testing.T) {
+	withProcTree(t,
+		testProcess{"87", "86", "python3"},
+		testProcess{"86", "12", "bash"},
+		testProcess{"12", "1", "runc"},
+		testProcess{"1", "0", "conmon"},
+	)
+
+	want := []string{"bash", "runc", "conmon"}
+	if got := processChain(maxAncestors); !reflect.DeepEqual(got, want) {
+		t.Errorf("processChain() = %q, want %q", got, want)
+	}
+
+	if got := processChain(2); !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("processChain(2) = %q, want %q", got, want[:2])
+	}
+}
+
+func TestProcessChainLoop(t *testing.T) {
+	withProcTree(t,
+		testProcess{"20", "21", "a"},
+		testProcess{"21", "20", "b"},
+	)
+
+	if got := processChain(maxAncestors); len(got) != maxAncestors {
+		t.Errorf("processChain() returned %d ancestors for a cycle, want the %d bound", len(got), maxAncestors)
+	}
+}
+
+func TestParseStartTime(t *testing.T) {
+	tests := []struct {
+		name   string
+		stat   string
+		want   uint64
+		wantOK bool
+	}{
+		{"simple", "1 (app) S 0 1 1 0 -1 4194560 1529 0 0 0 3 2 0 0 20 0 4 0 52863 729645056 3012 18446744073709551615\n", 52863, true},
+		{"spaces in comm", "1 (my app) (v2) S 0 1 1 0 -1 4194560 1529 0 0 0 3 2 0 0 20 0 4 0 123456 729645056 3012\n", 123456, true},
+		{"truncated", "1 (app) S 0 1 1\n", 0, false},
+		{"malformed", "garbage", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseStartTime(tt.stat)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseStartTime() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGetContainerStartedAt(t *testing.T) {
+	dir := withProcTree(t, testProcess{"1", "0", "app"})
+	writeTestFile(t, dir, "stat", "cpu  2255 34 2290 22625563 6290 127 456 0 0 0\nbtime 1700000000\nprocesses 1234\n")
+	writeTestFile(t, dir, "1/stat", "1 (app) S 0 1 1 0 -1 4194560 1529 0 0 0 3 2 0 0 20 0 4 0 52863 729645056 3012\n")
+
+	started := getContainerStartedAt()
+	if started == nil {
+		t.Fatal("getContainerStartedAt() = nil")
+	}
+
+	want := time.Unix(1700000000, 0).Add(528630 * time.Millisecond)
+	if !started.Equal(want) {
+		t.Errorf("getContainerStartedAt() = %v, want %v", started, want)
+	}
+
+	if got := containerUptime(started); got <= 0 {
+		t.Errorf("containerUptime() = %d, want a positive number of seconds", got)
+	}
+
+	if got := containerUptime(nil); got != 0 {
+		t.Errorf("containerUptime(nil) = %d, want 0", got)
+	}
+}
+
+func TestGetContainerStartedAtMissing(t *testing.T) {
+	withProcTree(t, testProcess{"1", "0", "app"})
+
+	if got := getContainerStartedAt(); got != nil {
+		t.Errorf("getContainerStartedAt() = %v without stat files, want nil", got)
+	}
+}
+
+func TestIsSystemdInContainer(t *testing.T) {
+	tests := []struct {
+		name    string
+		runtime string
+		init    string
+		marker  bool
+		want    bool
+	}{
+		{"system container", runtimeLXC, "systemd", true, true},
+		{"application container", runtimeDocker, "nginx", false, false},
+		{"systemd without marker", runtimeDocker, "systemd", false, false},
+		{"host", runtimeUndetermined, "systemd", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withProcTree(t,
+				testProcess{"212", "1", "app"},
+				testProcess{"1", "0", tt.init},
+			)
+
+			marker := t.TempDir()
+			if !tt.marker {
+				marker = filepath.Join(marker, "missing")
+			}
+
+			old := systemdMarkerPath
+			systemdMarkerPath = marker
+			t.Cleanup(func() { systemdMarkerPath = old })
+
+			if got := isSystemdInContainer(tt.runtime); got != tt.want {
+				t.Errorf("isSystemdInContainer(%q) = %v, want %v", tt.runtime, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRuntimeInitInjected(t *testing.T) {
+	tests := []struct {
+		name    string
+		comm    string
+		cmdline string
+		want    bool
+	}{
+		{"docker --init", "docker-init", "/sbin/docker-init\x00--\x00/app\x00", true},
+		{"podman --init", "catatonit", "/run/podman-init\x00--\x00/app\x00", true},
+		{"app", "app", "/app\x00--serve\x00", false},
+		{"image tini", "tini", "/usr/bin/tini\x00--\x00/app\x00", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t,
+				testProcess{"7", "1", "app"},
+				testProcess{"1", "0", tt.comm},
+			)
+			writeTestFile(t, dir, "1/cmdline", tt.cmdline)
+
+			if got := isRuntimeInitInjected(); got != tt.want {
+				t.Errorf("isRuntimeInitInjected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetOpenFilesLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		soft, hard uint64
+		err        error
+		wantSoft   uint64
+		wantHard   uint64
+	}{
+		{"limits", 1024, 1048576, nil, 1024, 1048576},
+		{"error", 1024, 4096, errors.New("operation not permitted"), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := readNofileLimit
+			readNofileLimit = func() (uint64, uint64, error) { return tt.soft, tt.hard, tt.err }
+			t.Cleanup(func() { readNofileLimit = old })
+
+			soft, hard := getOpenFilesLimit()
+			if soft != tt.wantSoft || hard != tt.wantHard {
+				t.Errorf("getOpenFilesLimit() = %d, %d, want %d, %d", soft, hard, tt.wantSoft, tt.wantHard)
+			}
+		})
+	}
+}
+
+func TestGetInitCmdline(t *testing.T) {
+	dir := withProcTree(t, testProcess{"1", "0", "app"})
+	writeTestFile(t, dir, "1/cmdline", "/app\x00--db-password\x00hunter2\x00")
+
+	if got, want := getInitCmdline(false), []string{"/app"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("getInitCmdline(false) = %q, want %q", got, want)
+	}
+
+	if got, want := getInitCmdline(true), []string{"/app", "--db-password", "hunter2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("getInitCmdline(true) = %q, want %q", got, want)
+	}
+}
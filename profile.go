@@ -0,0 +1,49 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import "context"
+
+// Profile returns an Inventory like New, enriched with fields that are costly
+// or sensitive to gather: PID 1's full command line, the cloud account,
+// project, subscription, instance and region, the AKS flavor of Azure hosts,
+// the GKE cluster name and Workload Identity, ECS task and Fargate platform
+// metadata, the kubelet log path, the mounts backing writable paths and a
+// scratch directory. On a cloud VM or ECS task this queries the metadata
+// services New leaves alone, adding up to one probe timeout per service, and
+// the mount, log path and scratch directory lookups walk the filesystem.
+//
+// Network probes are bounded by ctx. If ctx is done first, the fields gathered
+// so far are returned with Partial set.
+func Profile(ctx context.Context, opts ...Option) *Inventory {
+	inv, _ := NewWithContext(ctx, append(opts, WithPartialResults(), withProfile())...)
+
+	return inv
+}
+
+// enrichProfile fills the fields of inv only gathered by Profile. Every cloud
+// metadata service query made during detection is made here.
+func enrichProfile(inv *Inventory, c *config) {
+	az := getAzureCompute(c)
+	gcp := getGCPIdentity(c)
+	ec2 := getAWSIdentity(c)
+	ecs := getECSTask(c, inv.ID)
+
+	inv.CloudAccountID = ec2.accountID()
+	inv.CloudInstanceID = getCloudInstanceID(az, gcp, ec2)
+	inv.CloudProject = gcp.projectID()
+	inv.CloudSubscriptionID = az.subscriptionID()
+	inv.ClusterName, inv.ClusterNameSource = getClusterName(c, inv.Scheduler)
+	inv.ECSContainerImage = ecs.containerImage(inv.ID)
+	inv.ECSContainerName = ecs.containerName(inv.ID)
+	inv.ECSTaskARN = ecs.arn()
+	inv.InitCmdline = getInitCmdline(true)
+	inv.LogPath = getLogPath(inv.Scheduler, inv.Hostname, inv.ID)
+	inv.Mounts = getMounts()
+	inv.PlatformVersion = getFargatePlatformVersion(c)
+	inv.Region = getCloudRegion(az, ec2)
+	inv.SchedulerFlavor = getSchedulerFlavor(inv.Scheduler, az)
+	inv.ScratchDir, inv.ScratchFSType = getScratchDir()
+	inv.WorkloadIdentity = getWorkloadIdentity(c, inv.Scheduler)
+}
@@ -0,0 +1,40 @@
+package criprof
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProfile(t *testing.T) {
+	withAWSIMDS(t, true)
+	withDMI(t, map[string]string{"sys_vendor": "Amazon EC2\n"})
+	withOverrides(t)
+	withEnvironment(t, map[string]string{overrideScheduler: "nomad"})
+
+	lean := NewWithOptions(WithTimeout(time.Second))
+
+	if lean.CloudProvider != cloudAWS {
+		t.Errorf("New().CloudProvider = %q, want %q from DMI", lean.CloudProvider, cloudAWS)
+	}
+
+	if lean.CloudAccountID != "" || lean.CloudInstanceID != "" || lean.Region != "" || lean.ScratchDir != "" {
+		t.Errorf("New() populated profile fields: account %q, instance %q, region %q, scratch %q",
+			lean.CloudAccountID, lean.CloudInstanceID, lean.Region, lean.ScratchDir)
+	}
+
+	p := Profile(context.Background(), WithTimeout(time.Second))
+
+	if p.CloudAccountID != "123456789012" || p.CloudInstanceID != "i-0a1b2c3d4e5f67890" || p.Region != "us-east-1" {
+		t.Errorf("Profile() = account %q, instance %q, region %q, want the EC2 identity document",
+			p.CloudAccountID, p.CloudInstanceID, p.Region)
+	}
+
+	if p.ScratchDir == "" {
+		t.Error("Profile().ScratchDir is empty, want a writable directory")
+	}
+
+	if p.Scheduler != lean.Scheduler || p.Runtime != lean.Runtime {
+		t.Errorf("Profile() = %s/%s, want the same detection as New() %s/%s", p.Runtime, p.Scheduler, lean.Runtime, lean.Scheduler)
+	}
+}
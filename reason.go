@@ -0,0 +1,59 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+// UndeterminedReason explains why an Inventory field is "undetermined".
+type UndeterminedReason string
+
+// Reasons a field may be undetermined.
+const (
+	// ReasonNoSignal means every check ran and none matched.
+	ReasonNoSignal UndeterminedReason = "no-signal"
+	// ReasonSkipped means checks that could have matched were disabled, such
+	// as network probes under WithoutNetwork.
+	ReasonSkipped UndeterminedReason = "skipped"
+	// ReasonError means a check failed with an error other than the signal
+	// being absent.
+	ReasonError UndeterminedReason = "error"
+)
+
+// Reason returns why the field with the given JSON name ("id", "image_format",
+// "runtime" or "scheduler") is undetermined, or "" if it was determined.
+func (i Inventory) Reason(field string) UndeterminedReason {
+	return i.reasons[field]
+}
+
+// undeterminedReason returns the reason a detected value is undetermined, or
+// "" if it was determined.
+func undeterminedReason(value string, err error, skipped bool) UndeterminedReason {
+	switch {
+	case err != nil:
+		return ReasonError
+	case value != "undetermined" && value != "":
+		return ""
+	case skipped:
+		return ReasonSkipped
+	}
+
+	return ReasonNoSignal
+}
+
+// overallConfidence returns the fraction of the primary detections (runtime,
+// scheduler and image format) that were determined, from 0 when none were to
+// 1 when all were. Detection does not weight individual signals, so each
+// determined value counts equally.
+func overallConfidence(values ...string) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	var determined int
+	for _, v := range values {
+		if undeterminedReason(v, nil, false) == "" {
+			determined++
+		}
+	}
+
+	return float64(determined) / float64(len(values))
+}
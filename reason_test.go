@@ -0,0 +1,64 @@
+package criprof
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUndeterminedReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		err     error
+		skipped bool
+		want    UndeterminedReason
+	}{
+		{"determined", runtimeDocker, nil, false, ""},
+		{"determined despite skipped probes", schedulerNomad, nil, true, ""},
+		{"no signal", runtimeUndetermined, nil, false, ReasonNoSignal},
+		{"skipped", schedulerUndetermined, nil, true, ReasonSkipped},
+		{"error", "", errors.New("permission denied"), false, ReasonError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := undeterminedReason(tt.value, tt.err, tt.skipped); got != tt.want {
+				t.Errorf("undeterminedReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInventoryReason(t *testing.T) {
+	withEnvironment(t, nil)
+
+	i := NewWithOptions(WithoutNetwork())
+
+	if i.Scheduler == schedulerUndetermined && i.Reason("scheduler") != ReasonSkipped {
+		t.Errorf("Reason(scheduler) = %q, want %q", i.Reason("scheduler"), ReasonSkipped)
+	}
+
+	if i.Runtime != runtimeUndetermined && i.Reason("runtime") != "" {
+		t.Errorf("Reason(runtime) = %q for determined runtime %q", i.Reason("runtime"), i.Runtime)
+	}
+}
+
+func TestOverallConfidence(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   float64
+	}{
+		{"fully determined", []string{runtimeDocker, schedulerKubernetes, formatDocker}, 1},
+		{"partially determined", []string{runtimeDocker, "undetermined", formatDocker}, 2.0 / 3},
+		{"nothing determined", []string{"undetermined", "undetermined", ""}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overallConfidence(tt.values...); got != tt.want {
+				t.Errorf("overallConfidence() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+// rkt stage1 flavors, which determine the isolation of the pod.
+const (
+	rktStage1CoreOS = "coreos" // systemd-nspawn container (default)
+	rktStage1KVM    = "kvm"    // Lightweight virtual machine
+	rktStage1Fly    = "fly"    // chroot only, no isolation
+)
+
+// getRktStage1 returns the stage1 flavor of an rkt pod, or "" if the runtime
+// is not rkt.
+func getRktStage1(runtime string) string {
+	if runtime != runtimeRkt {
+		return ""
+	}
+
+	return classifyRktStage1(isRktKVM(), processComm("1"))
+}
+
+// isRktKVM returns true if the pod runs under stage1-kvm. Its guest kernel is
+// booted with the pod's root filesystem shared over virtio 9p, and the lkvm or
+// QEMU hypervisor is an ancestor when the host PID namespace is visible. A
+// hypervisor alone says nothing, as any stage1 may run on a cloud VM.
+func isRktKVM() bool {
+	for _, param := range readKernelCmdline() {
+		if param == "rootfstype=9p" {
+			return true
+		}
+	}
+
+	return hasAncestor("lkvm") || hasAncestor("qemu-system")
+}
+
+// classifyRktStage1 infers the stage1 flavor. stage1-kvm runs the pod in a
+// VM, the default stage1 runs systemd as the pod's PID 1, and stage1-fly
+// runs the app directly.
+func classifyRktStage1(vm bool, pid1Comm string) string {
+	switch {
+	case vm:
+		return rktStage1KVM
+	case pid1Comm == "systemd":
+		return rktStage1CoreOS
+	}
+
+	return rktStage1Fly
+}
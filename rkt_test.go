@@ -0,0 +1,32 @@
+package criprof
+
+import "testing"
+
+func TestGetRktStage1(t *testing.T) {
+	tests := []struct {
+		name     string
+		runtime  string
+		cmdline  string
+		pid1Comm string
+		want     string
+	}{
+		{"kvm", runtimeRkt, "console=hvc0 root=/dev/root rw rootflags=rw,trans=virtio,version=9p2000.L rootfstype=9p", "systemd", rktStage1KVM},
+		{"coreos", runtimeRkt, "BOOT_IMAGE=/vmlinuz root=/dev/sda1", "systemd", rktStage1CoreOS},
+		{"fly", runtimeRkt, "", "etcd", rktStage1Fly},
+		{"not rkt", runtimeDocker, "rootfstype=9p", "systemd", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t, testProcess{"1", "0", tt.pid1Comm})
+			writeTestFile(t, dir, "cmdline", tt.cmdline+"\n")
+
+			// The default stage1 on a cloud VM is not stage1-kvm.
+			withDMI(t, map[string]string{"sys_vendor": "QEMU"})
+
+			if got := getRktStage1(tt.runtime); got != tt.want {
+				t.Errorf("getRktStage1() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+//go:build linux
+// +build linux
+
+package criprof
+
+import "syscall"
+
+// nofileLimit returns the soft and hard RLIMIT_NOFILE of the process.
+func nofileLimit() (uint64, uint64, error) {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return 0, 0, err
+	}
+
+	return rl.Cur, rl.Max, nil
+}
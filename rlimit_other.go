@@ -0,0 +1,14 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+//go:build !linux
+// +build !linux
+
+package criprof
+
+import "errors"
+
+// nofileLimit is unsupported outside Linux.
+func nofileLimit() (uint64, uint64, error) {
+	return 0, 0, errors.New("rlimit unsupported")
+}
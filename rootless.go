@@ -0,0 +1,82 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// getRootless returns true if the detected runtime is running rootless, in a
+// user namespace owned by an unprivileged user.
+func getRootless(runtime string) bool {
+	switch runtime {
+	case runtimeContainerD:
+		return isRootlessContainerd()
+	case runtimeDocker:
+		return isRootlessDocker()
+	}
+
+	return false
+}
+
+// isRootlessContainerd returns true if containerd is running rootless, as set
+// up by nerdctl, which places its socket under $XDG_RUNTIME_DIR rather than
+// /run/containerd.
+func isRootlessContainerd() bool {
+	dir, ok := EnvironmentVariables["XDG_RUNTIME_DIR"]
+	if !ok || dir == "" {
+		return false
+	}
+
+	// Check if the per-user containerd socket exists.
+	if _, err := os.Stat(filepath.Join(dir, "containerd", "containerd.sock")); err == nil {
+		return true
+	}
+
+	return false
+}
+
+// isRootlessDocker returns true if Docker is running rootless, as set up by
+// dockerd-rootless.sh. Its daemon socket lives under $XDG_RUNTIME_DIR and
+// RootlessKit maps container root to the single unprivileged user that started
+// the daemon. Rootful Docker with
+// userns-remap instead maps root into a subordinate ID range.
+func isRootlessDocker() bool {
+	if dir := EnvironmentVariables["XDG_RUNTIME_DIR"]; dir != "" {
+		// Check if the per-user Docker socket exists.
+		if _, err := os.Stat(filepath.Join(dir, "docker.sock")); err == nil {
+			return true
+		}
+	}
+
+	if host := EnvironmentVariables["DOCKER_HOST"]; strings.HasPrefix(host, "unix:///run/user/") {
+		return true
+	}
+
+	f, err := os.Open(filepath.Join(procPath, "self", "uid_map"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	return isRootlessIDMap(f)
+}
+
+// isRootlessIDMap returns true if a uid_map read from r maps root alone to an
+// unprivileged user, the layout RootlessKit creates.
+func isRootlessIDMap(r io.Reader) bool {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "0" && fields[1] != "0" && fields[2] == "1" {
+			return true
+		}
+	}
+
+	return false
+}
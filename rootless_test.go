@@ -0,0 +1,82 @@
+package criprof
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetRootlessContainerd(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "containerd"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	withEnvironment(t, map[string]string{"XDG_RUNTIME_DIR": dir})
+
+	if getRootless(runtimeContainerD) {
+		t.Error("getRootless(containerd) = true without a rootless socket")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "containerd", "containerd.sock"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if !getRootless(runtimeContainerD) {
+		t.Error("getRootless(containerd) = false with a rootless socket")
+	}
+
+	if getRootless(runtimeLXD) {
+		t.Error("getRootless(lxd) = true for a runtime without rootless detection")
+	}
+}
+
+func TestGetRootlessDocker(t *testing.T) {
+	const (
+		rootlessMap = "         0       1000          1\n         1     100000      65536\n"
+		remapMap    = "         0     231072      65536\n"
+		hostMap     = "         0          0 4294967295\n"
+	)
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		socket bool
+		parent string
+		uidMap string
+		want   bool
+	}{
+		{"user socket", nil, true, "dockerd", hostMap, true},
+		{"docker host", map[string]string{"DOCKER_HOST": "unix:///run/user/1000/docker.sock"}, false, "containerd-shim", hostMap, true},
+		{"rootlesskit uid map", nil, false, "sh", rootlessMap, true},
+		{"userns-remap", nil, false, "sh", remapMap, false},
+		{"rootful", map[string]string{"DOCKER_HOST": "unix:///var/run/docker.sock"}, false, "containerd-shim", hostMap, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xdg := t.TempDir()
+			if tt.socket {
+				writeTestFile(t, xdg, "docker.sock", "")
+			}
+
+			env := map[string]string{"XDG_RUNTIME_DIR": xdg}
+			for k, v := range tt.env {
+				env[k] = v
+			}
+			withEnvironment(t, env)
+
+			dir := withProcTree(t,
+				testProcess{"42", "7", "app"},
+				testProcess{"7", "1", tt.parent},
+				testProcess{"1", "0", "init"},
+			)
+			writeTestFile(t, dir, "42/uid_map", tt.uidMap)
+
+			if got := getRootless(runtimeDocker); got != tt.want {
+				t.Errorf("getRootless(docker) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,75 +4,199 @@
 package criprof
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
 // Detectable container runtimes.
 const (
 	runtimeDocker       = "docker"       // Docker
+	runtimeFirecracker  = "firecracker"  // Firecracker microVM
+	runtimeGVisor       = "gvisor"       // gVisor (runsc)
+	runtimeIgnite       = "ignite"       // Weave Ignite (Firecracker microVM)
+	runtimeKata         = "kata"         // Kata Containers (lightweight VM)
 	runtimeRkt          = "rkt"          // CoreOS rkt
 	runtimeRunC         = "runc"         // Open Container Initiative runc
 	runtimeContainerD   = "containerd"   // containerd
 	runtimeLXC          = "lxc"          // LXC (Linux Containers)
 	runtimeLXD          = "lxd"          // LXD (containerd + LXC)
+	runtimeNspawn       = "nspawn"       // systemd-nspawn
+	runtimePodman       = "podman"       // Podman
 	runtimeOpenVZ       = "openvz"       // OpenVZ
 	runtimeWASM         = "wasm"         // Web Assembly
 	runtimeUndetermined = "undetermined" // Undetermined
 )
 
+// Marker files Docker places at the root of its containers.
+var (
+	dockerInitPath = "/.dockerinit"
+	dockerEnvPath  = "/.dockerenv"
+)
+
+// RuntimePreference ranks runtimes that should win when more than one runtime
+// is detected, such as Podman running under Kubernetes on a Docker host. The
+// first detected runtime in the list is reported; if none of the detected
+// runtimes are listed the built-in precedence applies.
+var RuntimePreference []string
+
 // getRuntime returns the name of the container runtime that is currently running.
-func getRuntime() string {
+func getRuntime(c *config) string {
+	// Check if the runtime has been forced by CRIPROF_FORCE_RUNTIME.
+	if v, ok := override(c, overrideRuntime); ok {
+		return v
+	}
+
+	return preferRuntime(detectRuntimes(c), RuntimePreference)
+}
+
+// detectRuntimes returns every runtime with a positive signal, ordered by the
+// built-in precedence.
+func detectRuntimes(c *config) []string {
+	var runtimes []string
+
+	add := func(r string) {
+		for _, existing := range runtimes {
+			if existing == r {
+				return
+			}
+		}
+		runtimes = append(runtimes, r)
+	}
+
+	// Check the container= variable systemd-aware runtimes set for PID 1.
+	if r := runtimeFromContainerEnv(getContainerEnv()); r != "" {
+		add(r)
+	}
+
+	if isGVisor() {
+		add(runtimeGVisor)
+	}
+
+	if isIgnite() {
+		add(runtimeIgnite)
+	}
+
+	if isKata() {
+		add(runtimeKata)
+	}
+
+	if isFirecracker() {
+		add(runtimeFirecracker)
+	}
+
 	// Check if the /.dockerinit file exists to detect a Docker runtime.
-	if _, err := os.Stat("/.dockerinit"); err == nil {
-		return runtimeDocker
+	if _, err := os.Stat(dockerInitPath); err == nil {
+		add(runtimeDocker)
 	}
 
 	// Check if the /.dockerenv file exists to detect a Docker runtime.
-	if _, err := os.Stat("/.dockerenv"); err == nil {
-		return runtimeDocker
+	if _, err := os.Stat(dockerEnvPath); err == nil {
+		add(runtimeDocker)
+	}
+
+	// Check if conmon, the container monitor Podman runs each container
+	// under, is an ancestor. CRI-O also uses conmon, but under Kubernetes.
+	podman := isPodmanConmon()
+	if podman {
+		add(runtimePodman)
 	}
 
 	// Check if /run/.containerenv file exists to detect a CRI-O or containerd
-	// runtime.
-	if _, err := os.Stat("/run/.containerenv"); err == nil {
-		return runtimeContainerD
+	// runtime, unless a conmon ancestor has already attributed it to Podman.
+	if _, err := os.Stat(containerEnvPath); err == nil && !podman {
+		add(runtimeContainerD)
+	}
+
+	// Check if a runwasi shim, which runs Wasm modules under containerd, is an
+	// ancestor of this process.
+	if getWasmEngine() != "" {
+		add(runtimeWASM)
+	}
+
+	// Check if a containerd shim is an ancestor of this process.
+	if hasAncestor("containerd-shim") {
+		add(runtimeContainerD)
 	}
 
 	// Check the cgroup to detect a Docker runtime.
-	cgroup, _ := ioutil.ReadFile("/proc/self/cgroup")
-	if strings.Contains(string(cgroup), "docker") {
-		return runtimeDocker
+	if r := parseCgroupRuntime(strings.NewReader(readCgroup(c))); r != "" {
+		add(r)
+	}
+
+	// Check the storage path of the root overlay mount.
+	if r := runtimeFromOverlay(); r != "" {
+		add(r)
 	}
 
 	// Check if the AC_METADATA_URL environment variable is set to detect an rkt runtime.
 	if _, ok := EnvironmentVariables["AC_METADATA_URL"]; ok {
-		return runtimeRkt
+		add(runtimeRkt)
 	}
 
 	// Check if the AC_APP_NAME environment variable is set to detect an rkt runtime.
 	if _, ok := EnvironmentVariables["AC_APP_NAME"]; ok {
-		return runtimeRkt
+		add(runtimeRkt)
 	}
 
 	// Check if the /dev/lxd/sock file exists to detect an LXD runtime.
-	if _, err := os.Stat("/dev/lxd/sock"); err == nil {
-		return runtimeLXD
+	if _, err := os.Stat(lxdSocketPath); err == nil {
+		add(runtimeLXD)
 	}
 
 	if isOpenVZ() {
-		return runtimeOpenVZ
+		add(runtimeOpenVZ)
 	}
 
 	if isWASM() {
-		return runtimeWASM
+		add(runtimeWASM)
 	}
 
-	// If none of the above checks pass, return an undetermined runtime.
+	return runtimes
+}
+
+// preferRuntime returns the first runtime in preference that was detected,
+// falling back to the first detected runtime. If nothing was detected the
+// runtime is undetermined.
+func preferRuntime(detected, preference []string) string {
+	for _, p := range preference {
+		for _, r := range detected {
+			if r == p {
+				return r
+			}
+		}
+	}
+
+	if len(detected) > 0 {
+		return detected[0]
+	}
+
+	// If none of the checks pass, return an undetermined runtime.
 	return runtimeUndetermined
 }
 
+// igniteMarkerPath is the configuration directory Ignite places in its VMs.
+var igniteMarkerPath = "/etc/ignite"
+
+// isIgnite returns true if running in a Weave Ignite microVM.
+func isIgnite() bool {
+	// Check if the /etc/ignite directory exists.
+	if _, err := os.Stat(igniteMarkerPath); err == nil {
+		return true
+	}
+
+	// Check if the DMI product identifies Ignite.
+	for _, field := range []string{"product_name", "product_serial"} {
+		if v, err := readDMI(field); err == nil && strings.Contains(strings.ToLower(v), "ignite") {
+			return true
+		}
+	}
+
+	return false
+}
+
 // isOpenVZ returns true if the program is running inside an OpenVZ container.
 func isOpenVZ() bool {
 	// Check if the /proc/vz directory exists.
@@ -91,3 +215,125 @@ func isWASM() bool {
 
 	return false
 }
+
+// wasmShimEngines maps the containerd shims built on runwasi to the Wasm engine
+// they embed.
+var wasmShimEngines = map[string]string{
+	"containerd-shim-wasmtime": "wasmtime",
+	"containerd-shim-wasmedge": "wasmedge",
+	"containerd-shim-wasmer":   "wasmer",
+	"containerd-shim-spin":     "spin",
+	"containerd-shim-slight":   "slight",
+}
+
+// getWasmEngine returns the Wasm engine of the runwasi shim running this
+// process, or "" if it is not running under one.
+func getWasmEngine() string {
+	args := ancestorCmdline("containerd-shim-")
+	if args == nil {
+		return ""
+	}
+
+	return wasmEngineFromShim(filepath.Base(args[0]))
+}
+
+// wasmEngineFromShim returns the Wasm engine embedded by the named containerd
+// shim, such as containerd-shim-spin-v2, or "" if it is not a runwasi shim.
+func wasmEngineFromShim(shim string) string {
+	for prefix, engine := range wasmShimEngines {
+		if strings.HasPrefix(shim, prefix+"-") || shim == prefix {
+			return engine
+		}
+	}
+
+	return ""
+}
+
+// getContainerEnv returns the value of the container= environment variable of
+// PID 1, which systemd-aware runtimes set to identify themselves. Under
+// hostPID, PID 1 is the host's init and is not consulted.
+func getContainerEnv() string {
+	if isHostPID() {
+		return ""
+	}
+
+	environ, err := ioutil.ReadFile(filepath.Join(procPath, "1", "environ"))
+	if err != nil {
+		return ""
+	}
+
+	return parseContainerEnv(environ)
+}
+
+// parseContainerEnv returns the value of container= from a NUL-separated
+// environ block.
+func parseContainerEnv(environ []byte) string {
+	for _, pair := range bytes.Split(environ, []byte{0}) {
+		if bytes.HasPrefix(pair, []byte("container=")) {
+			return string(pair[len("container="):])
+		}
+	}
+
+	return ""
+}
+
+// runtimeFromContainerEnv maps a container= value to a runtime. Values that are
+// not container runtimes, such as "wsl", or that are unknown return "".
+func runtimeFromContainerEnv(v string) string {
+	switch v {
+	case "docker":
+		return runtimeDocker
+	case "podman":
+		return runtimePodman
+	case "lxc", "lxc-libvirt":
+		return runtimeLXC
+	case "systemd-nspawn":
+		return runtimeNspawn
+	case "oci":
+		return runtimeRunC
+	case "rkt":
+		return runtimeRkt
+	}
+
+	return ""
+}
+
+// overlayStoragePaths maps container storage directories, as they appear in
+// the root overlay mount's upperdir and lowerdir options, to their runtime.
+var overlayStoragePaths = []struct {
+	path    string
+	runtime string
+}{
+	{"/var/lib/docker/", runtimeDocker},
+	{"/var/lib/containerd/", runtimeContainerD},
+	{"/var/lib/containers/", runtimePodman},
+	{"/.local/share/containers/", runtimePodman},
+}
+
+// runtimeFromOverlay returns the runtime owning the root overlay mount, or ""
+// if the root filesystem is not an overlay mount of a known runtime.
+func runtimeFromOverlay() string {
+	entries, err := readMountInfo()
+	if err != nil {
+		return ""
+	}
+
+	return runtimeFromMounts(entries)
+}
+
+// runtimeFromMounts returns the runtime owning the root overlay mount in
+// entries.
+func runtimeFromMounts(entries []mountEntry) string {
+	root, ok := findMount(entries, "/")
+	if !ok || root.Mountpoint != "/" || root.FSType != "overlay" {
+		return ""
+	}
+
+	for _, s := range overlayStoragePaths {
+		if strings.Contains(root.SuperOptions, s.path) {
+			return s.runtime
+		}
+	}
+
+	return ""
+}
@@ -1,10 +1,206 @@
 package criprof
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 func BenchmarkGetRuntime(b *testing.B) {
 	// Run getRuntime function b.N times.
 	for i := 0; i < b.N; i++ {
-		getRuntime()
+		getRuntime(newConfig())
+	}
+}
+
+func TestParseContainerEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ string
+		value   string
+		runtime string
+	}{
+		{"docker", "PATH=/usr/bin\x00container=docker\x00HOME=/", "docker", runtimeDocker},
+		{"podman", "container=podman\x00", "podman", runtimePodman},
+		{"lxc", "HOME=/\x00container=lxc", "lxc", runtimeLXC},
+		{"nspawn", "container=systemd-nspawn", "systemd-nspawn", runtimeNspawn},
+		{"wsl", "container=wsl", "wsl", ""},
+		{"unknown", "container=sandboxd", "sandboxd", ""},
+		{"absent", "PATH=/usr/bin\x00HOME=/", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := parseContainerEnv([]byte(tt.environ))
+			if v != tt.value {
+				t.Errorf("parseContainerEnv() = %q, want %q", v, tt.value)
+			}
+
+			if r := runtimeFromContainerEnv(v); r != tt.runtime {
+				t.Errorf("runtimeFromContainerEnv(%q) = %q, want %q", v, r, tt.runtime)
+			}
+		})
+	}
+}
+
+func TestPreferRuntime(t *testing.T) {
+	tests := []struct {
+		name       string
+		detected   []string
+		preference []string
+		want       string
+	}{
+		{"preferred wins", []string{runtimeDocker, runtimePodman}, []string{runtimePodman}, runtimePodman},
+		{"preference order", []string{runtimeDocker, runtimePodman}, []string{runtimeDocker, runtimePodman}, runtimeDocker},
+		{"unlisted falls back", []string{runtimeDocker, runtimeLXD}, []string{runtimePodman}, runtimeDocker},
+		{"no preference", []string{runtimeContainerD}, nil, runtimeContainerD},
+		{"nothing detected", nil, []string{runtimePodman}, runtimeUndetermined},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preferRuntime(tt.detected, tt.preference); got != tt.want {
+				t.Errorf("preferRuntime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuntimeFromMounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		mountinfo string
+		want      string
+	}{
+		{
+			"docker",
+			"1197 1103 0:113 / / rw,relatime - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/l/AB:/var/lib/docker/overlay2/l/CD,upperdir=/var/lib/docker/overlay2/9f/diff,workdir=/var/lib/docker/overlay2/9f/work\n",
+			runtimeDocker,
+		},
+		{
+			"containerd",
+			"2410 2288 0:312 / / rw,relatime - overlay overlay rw,lowerdir=/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs/snapshots/41/fs,upperdir=/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs/snapshots/58/fs,workdir=/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs/snapshots/58/work\n",
+			runtimeContainerD,
+		},
+		{
+			"podman",
+			"612 540 0:52 / / rw,relatime - overlay overlay rw,lowerdir=/var/lib/containers/storage/overlay/l/XY,upperdir=/var/lib/containers/storage/overlay/7c/diff,workdir=/var/lib/containers/storage/overlay/7c/work\n",
+			runtimePodman,
+		},
+		{
+			"rootless podman",
+			"612 540 0:52 / / rw,relatime - overlay overlay rw,lowerdir=/home/dev/.local/share/containers/storage/overlay/l/XY,upperdir=/home/dev/.local/share/containers/storage/overlay/7c/diff\n",
+			runtimePodman,
+		},
+		{
+			"not overlay",
+			"28 1 254:0 / / rw,relatime - ext4 /dev/vda rw\n",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, _ := parseMountInfo(strings.NewReader(tt.mountinfo))
+			if got := runtimeFromMounts(entries); got != tt.want {
+				t.Errorf("runtimeFromMounts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsIgnite(t *testing.T) {
+	tests := []struct {
+		name   string
+		marker bool
+		dmi    map[string]string
+		want   bool
+	}{
+		{"marker directory", true, nil, true},
+		{"dmi serial", false, map[string]string{"product_serial": "ignite-7d3e9f2a1b4c5d6e\n"}, true},
+		{"firecracker", false, map[string]string{"product_name": "Firecracker\n"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			marker := filepath.Join(dir, "ignite")
+			if tt.marker {
+				writeTestFile(t, marker, "ignite.yaml", "")
+			}
+
+			old := igniteMarkerPath
+			igniteMarkerPath = marker
+			t.Cleanup(func() { igniteMarkerPath = old })
+
+			withDMI(t, tt.dmi)
+
+			if got := isIgnite(); got != tt.want {
+				t.Errorf("isIgnite() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetContainerEnvHostPID(t *testing.T) {
+	dir := withProcTree(t,
+		testProcess{"4211", "0", "app"},
+		testProcess{"1", "0", "systemd"},
+	)
+	writeTestFile(t, dir, "1/environ", "container=podman\x00")
+	writeTestNamespace(t, dir, "4211/ns/mnt", "mnt:[4026532282]")
+	writeTestNamespace(t, dir, "1/ns/mnt", "mnt:[4026532282]")
+
+	if got := getContainerEnv(); got != "podman" {
+		t.Errorf("getContainerEnv() = %q, want %q", got, "podman")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "1/ns/mnt")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestNamespace(t, dir, "1/ns/mnt", "mnt:[4026531841]")
+
+	if got := getContainerEnv(); got != "" {
+		t.Errorf("getContainerEnv() under hostPID = %q, want empty", got)
+	}
+}
+
+func TestGetWasmEngine(t *testing.T) {
+	tests := []struct {
+		name string
+		shim string
+		want string
+	}{
+		{"wasmtime", "/usr/local/bin/containerd-shim-wasmtime-v1", "wasmtime"},
+		{"spin", "/usr/local/bin/containerd-shim-spin-v2", "spin"},
+		{"wasmedge", "containerd-shim-wasmedge-v1", "wasmedge"},
+		{"runc", "/usr/bin/containerd-shim-runc-v2", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t,
+				testProcess{"4211", "4102", "app"},
+				testProcess{"4102", "1", "containerd-shim"},
+				testProcess{"1", "0", "systemd"},
+			)
+			writeTestFile(t, dir, "4102/cmdline", tt.shim+"\x00-namespace\x00k8s.io\x00")
+
+			if got := getWasmEngine(); got != tt.want {
+				t.Errorf("getWasmEngine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetWasmEngineWithoutShim(t *testing.T) {
+	withProcTree(t,
+		testProcess{"4211", "1", "app"},
+		testProcess{"1", "0", "systemd"},
+	)
+
+	if got := getWasmEngine(); got != "" {
+		t.Errorf("getWasmEngine() = %q without a shim, want empty", got)
 	}
 }
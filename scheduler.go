@@ -4,15 +4,19 @@
 package criprof
 
 import (
-	"io/ioutil"
-	"net"
 	"net/http"
 	"os"
 	"strings"
 )
 
 const (
+	schedulerAppRunner    = "app-runner"
+	schedulerCloudRun     = "cloud-run"
+	schedulerCloudRunJob  = "cloud-run-job"
+	schedulerECS          = "ecs"
+	schedulerEKSFargate   = "eks-fargate"
 	schedulerKubernetes   = "kubernetes"
+	schedulerLambda       = "lambda"
 	schedulerNomad        = "nomad"
 	scehdulerMesos        = "mesos"
 	schedulerSwarm        = "swarm"
@@ -20,40 +24,167 @@ const (
 )
 
 // getScheduler returns the identified scheduler, if detected.
-func getScheduler() string {
-	if isKubernetes() {
-		return schedulerKubernetes
+func getScheduler(c *config) string {
+	// Check if the scheduler has been forced by CRIPROF_FORCE_SCHEDULER.
+	if v, ok := override(c, overrideScheduler); ok {
+		return v
+	}
+
+	// A kind node container is itself a Kubernetes node, running under the
+	// Docker or Podman runtime reported as Inventory.Runtime.
+	if isKubernetes(c) || isKindNode() {
+		return kubernetesScheduler()
+	}
+
+	if isCloudRunJob() {
+		return schedulerCloudRunJob
+	}
+
+	if isCloudRun() {
+		return schedulerCloudRun
+	}
+
+	if isLambda() {
+		return schedulerLambda
+	}
+
+	if isECS() {
+		return schedulerECS
+	}
+
+	if getAWSExecutionEnv() == awsAppRunner {
+		return schedulerAppRunner
 	}
 
 	if isNomad() {
 		return schedulerNomad
 	}
 
-	if isSwarm() {
+	if isSwarm(c) {
 		return schedulerSwarm
 	}
 
-	if isMesos() {
+	if isMesos(c) {
 		return scehdulerMesos
 	}
 
+	// A pause ancestor is the weakest signal, so it is only a fallback once
+	// every other scheduler has been ruled out.
+	if hasPauseAncestor() {
+		return kubernetesScheduler()
+	}
+
 	return schedulerUndetermined
 }
 
+// kubernetesScheduler returns the scheduler to report once Kubernetes has been
+// detected, distinguishing EKS on Fargate.
+func kubernetesScheduler() string {
+	if isEKSFargate() {
+		return schedulerEKSFargate
+	}
+
+	return schedulerKubernetes
+}
+
+// isKubernetesScheduler returns true if scheduler is Kubernetes, including
+// EKS on Fargate, where pods carry the same service account, DNS and CRI
+// layout.
+func isKubernetesScheduler(scheduler string) bool {
+	return scheduler == schedulerKubernetes || scheduler == schedulerEKSFargate
+}
+
+// Scheduler flavors refining Inventory.Scheduler.
+const (
+	flavorAKS          = "aks"           // Azure Kubernetes Service
+	flavorECSEC2       = "ec2"           // ECS on EC2 container instances
+	flavorECSFargate   = "fargate"       // ECS on Fargate
+	flavorGKE          = "gke"           // GKE Standard
+	flavorGKEAutopilot = "gke-autopilot" // GKE Autopilot
+	flavorKind         = "kind"          // Kubernetes in Docker
+)
+
+// kindMarkerPath is the directory kind bakes into its node image.
+var kindMarkerPath = "/kind"
+
+// getSchedulerFlavor returns the managed distribution of the detected
+// scheduler, or "" if it cannot be determined. AKS is also reported for
+// host-level workloads on an AKS node, which have no Kubernetes markers, from
+// the Azure metadata az that Profile fetches.
+func getSchedulerFlavor(scheduler string, az *azureCompute) string {
+	if az.isAKS() {
+		return flavorAKS
+	}
+
+	if scheduler == schedulerECS {
+		switch getAWSExecutionEnv() {
+		case awsECSFargate:
+			return flavorECSFargate
+		case awsECSEC2:
+			return flavorECSEC2
+		}
+
+		return ""
+	}
+
+	if scheduler != schedulerKubernetes {
+		return ""
+	}
+
+	if isKindNode() {
+		return flavorKind
+	}
+
+	// Check the node name; Autopilot nodes are named gk3-<cluster>-<pool>,
+	// Standard nodes gke-<cluster>-<pool>, and nodes of kind's default cluster
+	// kind-control-plane and kind-worker<n>.
+	node := getNodeName()
+	switch {
+	case strings.HasPrefix(node, "gk3-"):
+		return flavorGKEAutopilot
+	case strings.HasPrefix(node, "gke-"):
+		return flavorGKE
+	case strings.HasPrefix(node, "kind-"):
+		return flavorKind
+	}
+
+	return ""
+}
+
+// isKindNode returns true if running in a kind node container, where the
+// kubelet and control plane run nested inside a Docker container.
+func isKindNode() bool {
+	_, err := os.Stat(kindMarkerPath)
+	return err == nil
+}
+
 // isSwarm returns true if running in Docker Swarm.
-func isSwarm() bool {
-	// Check Docker Swarm port is open to detect if Docker Swarm cluster.
-	conn, err := net.Dial("tcp", "127.0.0.1:2377")
-	if err == nil {
-		conn.Close()
-		return true
+func isSwarm(c *config) bool {
+	if !c.network {
+		return false
 	}
 
-	return false
+	// Check the swarm state reported by a mounted Docker socket, which covers
+	// worker nodes and opens no network connection.
+	if info := getDockerInfo(c); info != nil {
+		return info.Swarm.LocalNodeState == "active"
+	}
+
+	// Check Docker Swarm port is open to detect if Docker Swarm cluster. Only
+	// manager nodes listen on it.
+	return c.probe(func() bool {
+		conn, err := c.dial("127.0.0.1:2377")
+		if err != nil {
+			return false
+		}
+
+		conn.Close()
+		return true
+	})
 }
 
 // isKubernetes returns true if running in Kubernetes cluster.
-func isKubernetes() bool {
+func isKubernetes(c *config) bool {
 	// Check if /run/secrets/kubernetes.io/serviceaccount/token file exists.
 	if _, err := os.Stat("/run/secrets/kubernetes.io/serviceaccount/token"); err == nil {
 		return true
@@ -64,14 +195,21 @@ func isKubernetes() bool {
 		return true
 	}
 
+	if !c.network {
+		return false
+	}
+
 	// Check if Kubernetes API server is accessible.
-	resp, err := http.Get("http://kubernetes.default.svc")
-	if err == nil {
+	client := &http.Client{Timeout: c.probeTimeout()}
+	return c.probe(func() bool {
+		resp, err := c.get(client, "http://kubernetes.default.svc")
+		if err != nil {
+			return false
+		}
+
 		resp.Body.Close()
 		return true
-	}
-
-	return false
+	})
 }
 
 // isNomad returns true if running inside a HashiCorp Nomad.
@@ -82,7 +220,7 @@ func isNomad() bool {
 	}
 
 	// Check if the HOSTNAME environment variable starts with the prefix "nomad-task-".
-	hostname, err := os.Hostname()
+	hostname, err := getHostname()
 	if err == nil && strings.HasPrefix(hostname, "nomad-task-") {
 		return true
 	}
@@ -91,7 +229,7 @@ func isNomad() bool {
 }
 
 // isMesos returns true if running in a Mesos environment.
-func isMesos() bool {
+func isMesos(c *config) bool {
 	// Check if  MESOS_TASK_ID environment variable is set.
 	if _, ok := EnvironmentVariables["MESOS_TASK_ID"]; ok {
 		return true
@@ -102,9 +240,32 @@ func isMesos() bool {
 		return true
 	}
 
-	// Check if the /proc/1/cgroup file contains the "mesos" string.
-	cgroup, err := ioutil.ReadFile("/proc/1/cgroup")
-	if err == nil && strings.Contains(string(cgroup), "mesos") {
+	// Check if the cgroup contains the "mesos" string.
+	if strings.Contains(readCgroup(c), "mesos") {
+		return true
+	}
+
+	return false
+}
+
+// isCloudRun returns true if running as a Google Cloud Run service.
+func isCloudRun() bool {
+	// Check if the K_SERVICE and K_REVISION environment variables are set.
+	_, service := EnvironmentVariables["K_SERVICE"]
+	_, revision := EnvironmentVariables["K_REVISION"]
+
+	return service && revision
+}
+
+// isCloudRunJob returns true if running as a Google Cloud Run job task.
+func isCloudRunJob() bool {
+	// Check if the CLOUD_RUN_JOB environment variable is set.
+	if _, ok := EnvironmentVariables["CLOUD_RUN_JOB"]; ok {
+		return true
+	}
+
+	// Check if the CLOUD_RUN_EXECUTION environment variable is set.
+	if _, ok := EnvironmentVariables["CLOUD_RUN_EXECUTION"]; ok {
 		return true
 	}
 
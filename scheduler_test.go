@@ -0,0 +1,81 @@
+package criprof
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCloudRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		service bool
+		job     bool
+	}{
+		{"service", map[string]string{"K_SERVICE": "api", "K_REVISION": "api-00042-xyz", "K_CONFIGURATION": "api"}, true, false},
+		{"job", map[string]string{"CLOUD_RUN_JOB": "nightly", "CLOUD_RUN_EXECUTION": "nightly-8xk2p", "CLOUD_RUN_TASK_INDEX": "0"}, false, true},
+		{"knative without revision", map[string]string{"K_SERVICE": "api"}, false, false},
+		{"none", nil, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEnvironment(t, tt.env)
+
+			if got := isCloudRun(); got != tt.service {
+				t.Errorf("isCloudRun() = %v, want %v", got, tt.service)
+			}
+
+			if got := isCloudRunJob(); got != tt.job {
+				t.Errorf("isCloudRunJob() = %v, want %v", got, tt.job)
+			}
+		})
+	}
+}
+
+func TestGetSchedulerFlavorGKE(t *testing.T) {
+	tests := []struct {
+		name      string
+		scheduler string
+		node      string
+		want      string
+	}{
+		{"autopilot", schedulerKubernetes, "gk3-prod-pool-2-8f4c1a2b-x7kq", flavorGKEAutopilot},
+		{"standard", schedulerKubernetes, "gke-prod-default-pool-5e6f7a8b-9c0d", flavorGKE},
+		{"kind pod", schedulerKubernetes, "kind-worker2", flavorKind},
+		{"other kubernetes", schedulerKubernetes, "ip-10-0-1-23.ec2.internal", ""},
+		{"not kubernetes", schedulerNomad, "gk3-prod-pool-2-8f4c1a2b-x7kq", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEnvironment(t, map[string]string{"NODE_NAME": tt.node})
+
+			if got := getSchedulerFlavor(tt.scheduler, nil); got != tt.want {
+				t.Errorf("getSchedulerFlavor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKindNode(t *testing.T) {
+	old := kindMarkerPath
+	kindMarkerPath = t.TempDir()
+	t.Cleanup(func() { kindMarkerPath = old })
+
+	withEnvironment(t, map[string]string{"container": "docker"})
+
+	if got := getScheduler(newConfig(WithoutNetwork())); got != schedulerKubernetes {
+		t.Errorf("getScheduler() = %q in a kind node, want %q", got, schedulerKubernetes)
+	}
+
+	if got := getSchedulerFlavor(schedulerKubernetes, nil); got != flavorKind {
+		t.Errorf("getSchedulerFlavor() = %q in a kind node, want %q", got, flavorKind)
+	}
+
+	kindMarkerPath = filepath.Join(kindMarkerPath, "missing")
+
+	if isKindNode() {
+		t.Error("isKindNode() = true without the /kind directory")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Seccomp profile names, following the Kubernetes securityContext naming.
+const (
+	seccompRuntimeDefault = "RuntimeDefault" // The runtime's default profile
+	seccompUnconfined     = "Unconfined"     // No seccomp filtering
+	seccompLocalhost      = "Localhost"      // A profile from the node
+	seccompStrict         = "Strict"         // Strict mode (read/write/exit only)
+	seccompFilter         = "Filter"         // An unnamed filter profile
+)
+
+// getSeccompProfile returns the seccomp profile applied to the container. The
+// profile name is taken from the Kubernetes seccomp annotations when exposed
+// through the Downward API; otherwise the kernel's Seccomp mode is reported.
+func getSeccompProfile() string {
+	if p := seccompProfileFromAnnotations(readPodInfo("annotations")); p != "" {
+		return p
+	}
+
+	mode, err := procStatusField("self", "Seccomp")
+	if err != nil {
+		return ""
+	}
+
+	return seccompProfileFromMode(mode)
+}
+
+// seccompProfileFromAnnotations returns the profile named by the pod or any
+// container seccomp annotation.
+func seccompProfileFromAnnotations(annotations map[string]string) string {
+	if v, ok := annotations["seccomp.security.alpha.kubernetes.io/pod"]; ok {
+		return seccompProfileName(v)
+	}
+
+	for k, v := range annotations {
+		if strings.HasPrefix(k, "container.seccomp.security.alpha.kubernetes.io/") {
+			return seccompProfileName(v)
+		}
+	}
+
+	return ""
+}
+
+// seccompProfileName maps an annotation value, such as "runtime/default" or
+// "localhost/profiles/audit.json", to its securityContext name.
+func seccompProfileName(v string) string {
+	switch {
+	case v == "runtime/default" || v == "docker/default":
+		return seccompRuntimeDefault
+	case v == "unconfined":
+		return seccompUnconfined
+	case strings.HasPrefix(v, "localhost/"):
+		return seccompLocalhost + "/" + strings.TrimPrefix(v, "localhost/")
+	}
+
+	return v
+}
+
+// seccompProfileFromMode maps the Seccomp field of /proc/self/status, which
+// does not carry a profile name, to the closest description.
+func seccompProfileFromMode(mode string) string {
+	switch mode {
+	case "0":
+		return seccompUnconfined
+	case "1":
+		return seccompStrict
+	case "2":
+		return seccompFilter
+	}
+
+	return ""
+}
+
+// Capability bit numbers from linux/capability.h.
+const (
+	capNetAdmin  = 12
+	capSysModule = 16
+	capSysPtrace = 19
+	capSysAdmin  = 21
+)
+
+// isEffectivelyPrivileged returns true if the process's effective capability
+// set is dangerous enough to treat the container as near-privileged, even
+// when it was not started with --privileged. It trips on CAP_SYS_ADMIN or
+// CAP_SYS_MODULE alone, which each allow escaping the container directly, or
+// on CAP_SYS_PTRACE together with CAP_NET_ADMIN, which together allow
+// inspecting and redirecting other workloads on shared namespaces.
+func isEffectivelyPrivileged() bool {
+	v, err := procStatusField("self", "CapEff")
+	if err != nil {
+		return false
+	}
+
+	caps, err := strconv.ParseUint(v, 16, 64)
+	if err != nil {
+		return false
+	}
+
+	return dangerousCapabilities(caps)
+}
+
+// dangerousCapabilities applies the isEffectivelyPrivileged heuristic to an
+// effective capability mask.
+func dangerousCapabilities(caps uint64) bool {
+	has := func(c uint) bool { return caps&(1<<c) != 0 }
+
+	if has(capSysAdmin) || has(capSysModule) {
+		return true
+	}
+
+	return has(capSysPtrace) && has(capNetAdmin)
+}
+
+// isPtraceRestricted returns true if the process cannot freely ptrace other
+// processes of the same user, so debuggers and profilers that attach to a
+// running process will fail. Yama's ptrace_scope restricts tracing to
+// descendants at 1, to CAP_SYS_PTRACE holders at 2, and disables it at 3;
+// seccomp strict mode forbids the ptrace syscall entirely.
+func isPtraceRestricted() bool {
+	if mode, err := procStatusField("self", "Seccomp"); err == nil && mode == "1" {
+		return true
+	}
+
+	scope, err := ioutil.ReadFile(filepath.Join(procPath, "sys", "kernel", "yama", "ptrace_scope"))
+	if err != nil {
+		return false
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(string(scope)))
+	return err == nil && n > 0
+}
@@ -0,0 +1,88 @@
+package criprof
+
+import "testing"
+
+func TestGetSeccompProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations string
+		mode        string
+		want        string
+	}{
+		{"runtime default annotation", "seccomp.security.alpha.kubernetes.io/pod=\"runtime/default\"\n", "2", seccompRuntimeDefault},
+		{"unconfined annotation", "container.seccomp.security.alpha.kubernetes.io/app=\"unconfined\"\n", "0", seccompUnconfined},
+		{"localhost annotation", "seccomp.security.alpha.kubernetes.io/pod=\"localhost/profiles/audit.json\"\n", "2", "Localhost/profiles/audit.json"},
+		{"unconfined mode", "", "0", seccompUnconfined},
+		{"filter mode", "app=\"web\"\n", "2", seccompFilter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t, testProcess{"42", "1", "app"})
+			writeTestFile(t, dir, "42/status", "Name:\tapp\nPPid:\t1\nSeccomp:\t"+tt.mode+"\nSeccomp_filters:\t1\n")
+			withPodInfo(t, map[string]string{"annotations": tt.annotations})
+
+			if got := getSeccompProfile(); got != tt.want {
+				t.Errorf("getSeccompProfile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsEffectivelyPrivileged(t *testing.T) {
+	tests := []struct {
+		name   string
+		capEff string
+		want   bool
+	}{
+		{"docker default", "00000000a80425fb", false},
+		{"privileged", "000001ffffffffff", true},
+		{"sys_admin", "00000000a82425fb", true},
+		{"sys_module", "00000000a80525fb", true},
+		{"sys_ptrace alone", "00000000a80c25fb", false},
+		{"net_admin alone", "00000000a80435fb", false},
+		{"sys_ptrace and net_admin", "00000000a80c35fb", true},
+		{"none", "0000000000000000", false},
+		{"malformed", "zz", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t, testProcess{"42", "1", "app"})
+			writeTestFile(t, dir, "42/status", "Name:\tapp\nCapInh:\t0000000000000000\nCapEff:\t"+tt.capEff+"\n")
+
+			if got := isEffectivelyPrivileged(); got != tt.want {
+				t.Errorf("isEffectivelyPrivileged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPtraceRestricted(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   string
+		seccomp string
+		want    bool
+	}{
+		{"classic", "0\n", "2", false},
+		{"restricted", "1\n", "2", true},
+		{"admin only", "2\n", "0", true},
+		{"no yama", "", "2", false},
+		{"seccomp strict", "0\n", "1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withProcTree(t, testProcess{"42", "1", "app"})
+			writeTestFile(t, dir, "42/status", "Name:\tapp\nSeccomp:\t"+tt.seccomp+"\n")
+			if tt.scope != "" {
+				writeTestFile(t, dir, "sys/kernel/yama/ptrace_scope", tt.scope)
+			}
+
+			if got := isPtraceRestricted(); got != tt.want {
+				t.Errorf("isPtraceRestricted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+)
+
+// serviceAccountTokenPath is where Kubernetes projects the pod's service
+// account token.
+var serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// getTokenAudience returns the audiences of the projected service account
+// token, or nil if not running under Kubernetes or the token is unreadable.
+// The token is decoded without verification and never retained.
+func getTokenAudience(scheduler string) []string {
+	if !isKubernetesScheduler(scheduler) {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(serviceAccountTokenPath)
+	if err != nil {
+		return nil
+	}
+
+	return parseTokenAudience(strings.TrimSpace(string(b)))
+}
+
+// parseTokenAudience returns the aud claim of a JWT, which may be a single
+// string or an array of strings.
+func parseTokenAudience(token string) []string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil
+	}
+
+	var claims struct {
+		Aud json.RawMessage `json:"aud"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || len(claims.Aud) == 0 {
+		return nil
+	}
+
+	var aud []string
+	if err := json.Unmarshal(claims.Aud, &aud); err == nil {
+		return aud
+	}
+
+	var single string
+	if err := json.Unmarshal(claims.Aud, &single); err == nil && single != "" {
+		return []string{single}
+	}
+
+	return nil
+}
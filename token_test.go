@@ -0,0 +1,57 @@
+package criprof
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testJWT(payload string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"RS256","kid":"k1"}`)) + "." +
+		enc.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+func TestParseTokenAudience(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  []string
+	}{
+		{"array", testJWT(`{"aud":["https://kubernetes.default.svc","vault"],"sub":"system:serviceaccount:prod:web"}`), []string{"https://kubernetes.default.svc", "vault"}},
+		{"string", testJWT(`{"aud":"sts.amazonaws.com"}`), []string{"sts.amazonaws.com"}},
+		{"no aud", testJWT(`{"sub":"system:serviceaccount:prod:web"}`), nil},
+		{"not a jwt", "opaque-token", nil},
+		{"bad payload", "a.!!!.c", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTokenAudience(tt.token); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTokenAudience() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTokenAudience(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "token", testJWT(`{"aud":["vault"]}`)+"\n")
+
+	old := serviceAccountTokenPath
+	serviceAccountTokenPath = filepath.Join(dir, "token")
+	t.Cleanup(func() { serviceAccountTokenPath = old })
+
+	if got := getTokenAudience(schedulerKubernetes); !reflect.DeepEqual(got, []string{"vault"}) {
+		t.Errorf("getTokenAudience(kubernetes) = %v, want [vault]", got)
+	}
+
+	if got := getTokenAudience(schedulerEKSFargate); !reflect.DeepEqual(got, []string{"vault"}) {
+		t.Errorf("getTokenAudience(eks-fargate) = %v, want [vault]", got)
+	}
+
+	if got := getTokenAudience(schedulerNomad); got != nil {
+		t.Errorf("getTokenAudience(nomad) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright © 2022-2023 Christian R. Vozar
+// Licensed under the MIT License. All rights reserved.
+
+package criprof
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// getUIDRemapped returns true if uid is mapped to a different user on the host
+// by the process's user namespace, as in rootless and userns-remap containers
+// where root inside the container is unprivileged outside it.
+func getUIDRemapped(uid int) bool {
+	f, err := os.Open(filepath.Join(procPath, "self", "uid_map"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	host, ok := parseIDMap(f, uid)
+
+	return ok && host != uid
+}
+
+// parseIDMap returns the ID outside the user namespace that id maps to, given
+// the "inside outside length" lines of a uid_map or gid_map file.
+func parseIDMap(r io.Reader, id int) (int, bool) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+
+		inside, err1 := strconv.ParseInt(fields[0], 10, 64)
+		outside, err2 := strconv.ParseInt(fields[1], 10, 64)
+		length, err3 := strconv.ParseInt(fields[2], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+
+		if int64(id) >= inside && int64(id) < inside+length {
+			return int(outside + int64(id) - inside), true
+		}
+	}
+
+	return 0, false
+}
+
+// isRunAsRoot returns true if uid is root and holds root's privileges on the
+// host, that is, it is not remapped by a user namespace.
+func isRunAsRoot(uid int, remapped bool) bool {
+	return uid == 0 && !remapped
+}
@@ -0,0 +1,49 @@
+package criprof
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseIDMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		idMap    string
+		id       int
+		wantHost int
+		wantOK   bool
+	}{
+		{"identity", "         0          0 4294967295\n", 0, 0, true},
+		{"rootless root", "         0       1000          1\n         1     100000      65536\n", 0, 1000, true},
+		{"rootless user", "         0       1000          1\n         1     100000      65536\n", 33, 100032, true},
+		{"unmapped", "         0     100000      65536\n", 70000, 0, false},
+		{"empty", "", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, ok := parseIDMap(strings.NewReader(tt.idMap), tt.id)
+			if host != tt.wantHost || ok != tt.wantOK {
+				t.Errorf("parseIDMap() = %d, %v, want %d, %v", host, ok, tt.wantHost, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsRunAsRoot(t *testing.T) {
+	dir := withProcTree(t, testProcess{"4211", "0", "app"})
+
+	writeTestFile(t, dir, "4211/uid_map", "         0          0 4294967295\n")
+	if remapped := getUIDRemapped(0); remapped || !isRunAsRoot(0, remapped) {
+		t.Errorf("root with an identity map: remapped = %v, want root on the host", remapped)
+	}
+
+	writeTestFile(t, dir, "4211/uid_map", "         0     100000      65536\n")
+	if remapped := getUIDRemapped(0); !remapped || isRunAsRoot(0, remapped) {
+		t.Errorf("root remapped to 100000: remapped = %v, want not root on the host", remapped)
+	}
+
+	if isRunAsRoot(1000, false) {
+		t.Error("isRunAsRoot(1000) = true")
+	}
+}
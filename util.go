@@ -10,14 +10,22 @@ import (
 
 // environMap returns the results of os.Environ as a map.
 func environMap() map[string]string {
-	env := os.Environ()
+	return parseEnviron(os.Environ())
+}
 
-	// Create empty map to store environment variables.
-	vars := make(map[string]string)
+// parseEnviron returns the KEY=value pairs of env as a map. Values may
+// themselves contain "=".
+func parseEnviron(env []string) map[string]string {
+	// Size the map up front; Kubernetes pods can carry thousands of service
+	// variables.
+	vars := make(map[string]string, len(env))
 
 	for _, pair := range env {
 		// Split each string into a key and a value.
-		e := strings.Split(pair, "=")
+		e := strings.SplitN(pair, "=", 2)
+		if len(e) != 2 {
+			continue
+		}
 		vars[e[0]] = e[1]
 	}
 	return vars
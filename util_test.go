@@ -0,0 +1,94 @@
+package criprof
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withEnvironment replaces the cached EnvironmentVariables for the duration
+// of the test.
+func withEnvironment(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	old := EnvironmentVariables
+	EnvironmentVariables = env
+	t.Cleanup(func() { EnvironmentVariables = old })
+}
+
+// writeTestFile writes contents to name beneath dir, creating parent
+// directories, and returns the file's path.
+func writeTestFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(p, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+// containsString returns true if s is an element of list.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestParseEnviron(t *testing.T) {
+	vars := parseEnviron([]string{
+		"KUBERNETES_SERVICE_HOST=10.96.0.1",
+		"JAVA_OPTS=-Dfoo=bar -Xmx512m",
+		"EMPTY=",
+		"MALFORMED",
+	})
+
+	want := map[string]string{
+		"KUBERNETES_SERVICE_HOST": "10.96.0.1",
+		"JAVA_OPTS":               "-Dfoo=bar -Xmx512m",
+		"EMPTY":                   "",
+	}
+
+	if len(vars) != len(want) {
+		t.Errorf("parseEnviron() = %v, want %v", vars, want)
+	}
+
+	for k, v := range want {
+		if got, ok := vars[k]; !ok || got != v {
+			t.Errorf("parseEnviron()[%s] = %q, %v, want %q", k, got, ok, v)
+		}
+	}
+}
+
+func BenchmarkParseEnviron(b *testing.B) {
+	// Kubernetes injects five variables per service in the namespace.
+	var env []string
+	for i := 0; i < 1000; i++ {
+		svc := fmt.Sprintf("SERVICE_%d", i)
+		env = append(env,
+			svc+"_SERVICE_HOST=10.96.12.34",
+			svc+"_SERVICE_PORT=8080",
+			svc+"_PORT=tcp://10.96.12.34:8080",
+			svc+"_PORT_8080_TCP_PROTO=tcp",
+			svc+"_PORT_8080_TCP_ADDR=10.96.12.34",
+		)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		parseEnviron(env)
+	}
+}